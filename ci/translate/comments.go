@@ -0,0 +1,74 @@
+package main
+
+import (
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// translationFileNode encodes the translation file into a YAML node tree
+// and carries over comments and styles of the nodes loaded from disk so
+// annotations made by hand survive saving
+func translationFileNode(tf translationFile) (*yaml.Node, error) {
+	var content yaml.Node
+	if err := content.Encode(tf); err != nil {
+		return nil, errors.Wrap(err, "encoding translation file")
+	}
+
+	node := &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{&content}}
+	if tf.source != nil {
+		mergeNodeComments(tf.source, node)
+	}
+
+	return node, nil
+}
+
+// mergeNodeComments copies comments and styles from the old node to the
+// corresponding new node, matching mapping entries by key and sequence
+// elements by index. Entries only present in the new tree are left
+// untouched, entries only present in the old tree are dropped along with
+// their comments.
+func mergeNodeComments(old, node *yaml.Node) {
+	node.HeadComment = old.HeadComment
+	node.LineComment = old.LineComment
+	node.FootComment = old.FootComment
+
+	if old.Kind != node.Kind {
+		return
+	}
+
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(old.Content) > 0 && len(node.Content) > 0 {
+			mergeNodeComments(old.Content[0], node.Content[0])
+		}
+
+	case yaml.MappingNode:
+		node.Style = old.Style
+
+		oldEntries := make(map[string][2]*yaml.Node, len(old.Content)/2)
+		for i := 0; i+1 < len(old.Content); i += 2 {
+			oldEntries[old.Content[i].Value] = [2]*yaml.Node{old.Content[i], old.Content[i+1]}
+		}
+
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			entry, ok := oldEntries[node.Content[i].Value]
+			if !ok {
+				continue
+			}
+			mergeNodeComments(entry[0], node.Content[i])
+			mergeNodeComments(entry[1], node.Content[i+1])
+		}
+
+	case yaml.SequenceNode:
+		node.Style = old.Style
+
+		for i := 0; i < len(old.Content) && i < len(node.Content); i++ {
+			mergeNodeComments(old.Content[i], node.Content[i])
+		}
+
+	case yaml.ScalarNode:
+		if old.Value == node.Value {
+			node.Style = old.Style
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSaveTranslationFileKeepsComments(t *testing.T) {
+	const commented = `# Translations of the app
+# maintained by the docs team
+
+reference:
+  languageKey: en
+  translations:
+    # Shown on the start page
+    greeting: Hello # keep it short
+    removed: Removed
+translations:
+  de:
+    translations:
+      # Informal on purpose
+      greeting: Hallo
+`
+
+	for _, tc := range []struct {
+		name     string
+		modify   func(tf *translationFile)
+		expected []string
+		absent   []string
+	}{
+		{
+			name: "unchanged",
+			expected: []string{
+				"# Translations of the app\n# maintained by the docs team\n",
+				"    # Shown on the start page\n    greeting: Hello # keep it short\n",
+				"      # Informal on purpose\n      greeting: Hallo\n",
+			},
+		},
+		{
+			name: "modified",
+			modify: func(tf *translationFile) {
+				delete(tf.Reference.Translations, "removed")
+				tf.Reference.Translations["added"] = "Added"
+				tf.Translations["de"].Translations["greeting"] = "Servus"
+			},
+			expected: []string{
+				"# Translations of the app\n# maintained by the docs team\n",
+				"    added: Added\n    # Shown on the start page\n    greeting: Hello # keep it short\n",
+				"      # Informal on purpose\n      greeting: Servus\n",
+			},
+			absent: []string{"removed"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			testConfig(t)
+
+			tf := loadTestFile(t, commented)
+			if tc.modify != nil {
+				tc.modify(&tf)
+			}
+
+			if err := saveTranslationFile(tf); err != nil {
+				t.Fatalf("saving: %s", err)
+			}
+
+			saved := readTestFile(t, cfg.TranslationFile)
+			for _, exp := range tc.expected {
+				if !strings.Contains(saved, exp) {
+					t.Errorf("saved file does not contain %q:\n%s", exp, saved)
+				}
+			}
+			for _, unexp := range tc.absent {
+				if strings.Contains(saved, unexp) {
+					t.Errorf("saved file contains %q:\n%s", unexp, saved)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"math"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+type languageCompleteness struct {
+	Lang       string
+	Total      int
+	Translated int
+}
+
+// calculateCompleteness compares the keys of the reference against the
+// keys present in each target language and returns the result sorted
+// by language key
+func calculateCompleteness(tf translationFile) []languageCompleteness {
+	var (
+		missing = missingKeys(tf)
+		result  []languageCompleteness
+	)
+
+	for _, lang := range tf.Languages() {
+		result = append(result, languageCompleteness{
+			Lang:       lang,
+			Total:      len(tf.Reference.Translations),
+			Translated: len(tf.Reference.Translations) - len(missing[lang]),
+		})
+	}
+
+	return result
+}
+
+// missingKeys returns the sorted list of reference keys not present in
+// each of the target languages
+func missingKeys(tf translationFile) map[string][]string {
+	missing := make(map[string][]string)
+
+	for lang, tm := range tf.Translations {
+		for _, key := range tf.ReferenceKeys() {
+			if tm.Translations[key] == nil {
+				missing[lang] = append(missing[lang], key)
+			}
+		}
+	}
+
+	return missing
+}
+
+// Percentage returns the share of translated keys in range 0-100
+func (l languageCompleteness) Percentage() float64 {
+	if l.Total == 0 {
+		return 100
+	}
+	return float64(l.Translated) / float64(l.Total) * 100
+}
+
+// logCompleteness prints the completeness of each language and returns
+// the languages falling below the given threshold
+func logCompleteness(completeness []languageCompleteness, threshold float64) (belowThreshold []string) {
+	for _, lc := range completeness {
+		logrus.WithFields(logrus.Fields{
+			"lang":       lc.Lang,
+			"percent":    math.Round(lc.Percentage()*10) / 10,
+			"total":      lc.Total,
+			"translated": lc.Translated,
+		}).Infof("translation completeness: %.1f%%", lc.Percentage())
+
+		if lc.Percentage() < threshold {
+			belowThreshold = append(belowThreshold, lc.Lang)
+		}
+	}
+
+	return belowThreshold
+}
+
+// checkMissingTranslations logs all missing translations and returns
+// their total count
+func checkMissingTranslations(tf translationFile) (total int) {
+	missing := missingKeys(tf)
+
+	for _, lang := range tf.Languages() {
+		for _, key := range missing[lang] {
+			logrus.WithFields(logrus.Fields{
+				"lang": lang,
+				"key":  key,
+			}).Warn("missing translation")
+		}
+		total += len(missing[lang])
+	}
+
+	return total
+}
+
+// reportMissingTranslations logs the number of missing translations per
+// language and, if verbose, the missing keys
+func reportMissingTranslations(tf translationFile, verbose bool) {
+	missing := missingKeys(tf)
+
+	for _, lang := range tf.Languages() {
+		fields := logrus.Fields{
+			"lang":    lang,
+			"missing": len(missing[lang]),
+		}
+		if verbose && len(missing[lang]) > 0 {
+			fields["keys"] = strings.Join(missing[lang], ", ")
+		}
+
+		logrus.WithFields(fields).Info("missing translations")
+	}
+}
+
+type (
+	completenessReport struct {
+		Languages map[string]languageReport `json:"languages"`
+		Totals    completenessTotals        `json:"totals"`
+	}
+
+	languageReport struct {
+		Missing    []string `json:"missing"`
+		Percentage float64  `json:"percentage"`
+		Total      int      `json:"total"`
+		Translated int      `json:"translated"`
+	}
+
+	completenessTotals struct {
+		Keys      int `json:"keys"`
+		Languages int `json:"languages"`
+		Missing   int `json:"missing"`
+	}
+)
+
+// writeReportFile writes the missing keys and completeness of every
+// language as JSON document into the given file
+func writeReportFile(tf translationFile, filename string) error {
+	var (
+		missing = missingKeys(tf)
+		report  = completenessReport{
+			Languages: make(map[string]languageReport),
+			Totals: completenessTotals{
+				Keys:      len(tf.Reference.Translations),
+				Languages: len(tf.Translations),
+			},
+		}
+	)
+
+	for _, lc := range calculateCompleteness(tf) {
+		report.Languages[lc.Lang] = languageReport{
+			Missing:    append([]string{}, missing[lc.Lang]...),
+			Percentage: lc.Percentage(),
+			Total:      lc.Total,
+			Translated: lc.Translated,
+		}
+		report.Totals.Missing += len(missing[lc.Lang])
+	}
+
+	return writeJSONFile(filename, report)
+}
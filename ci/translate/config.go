@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+// configFileFromArgs extracts the config file from the command line
+// arguments (or the CONFIG environment variable) as it needs to be
+// known before the remaining flags are parsed
+func configFileFromArgs(args []string) string {
+	for i, arg := range args {
+		switch {
+		case arg == "--":
+			return os.Getenv("CONFIG")
+		case arg == "--config" && i+1 < len(args):
+			return args[i+1]
+		case strings.HasPrefix(arg, "--config="):
+			return strings.TrimPrefix(arg, "--config=")
+		}
+	}
+
+	return os.Getenv("CONFIG")
+}
+
+// loadConfigFile reads the YAML (or JSON, being a subset of YAML) config
+// file and returns its values as variable defaults for the cfg fields,
+// keyed by the name of their flag
+func loadConfigFile(filename string) (map[string]string, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading config file")
+	}
+
+	var raw map[string]any
+	if err = yaml.Unmarshal(data, &raw); err != nil {
+		return nil, errors.Wrap(err, "decoding config file")
+	}
+
+	known := make(map[string]bool)
+	ct := reflect.TypeOf(cfg)
+	for i := 0; i < ct.NumField(); i++ {
+		if name := ct.Field(i).Tag.Get("vardefault"); name != "" {
+			known[name] = true
+		}
+	}
+
+	defaults := make(map[string]string, len(raw))
+	for key, value := range raw {
+		if !known[key] {
+			return nil, errors.Errorf("unknown setting %q in config file", key)
+		}
+
+		switch v := value.(type) {
+		case []any:
+			elems := make([]string, len(v))
+			for i := range v {
+				elems[i] = fmt.Sprint(v[i])
+			}
+			defaults[key] = strings.Join(elems, ",")
+
+		default:
+			defaults[key] = fmt.Sprint(v)
+		}
+	}
+
+	return defaults, nil
+}
+
+// applyDeprecatedFlags copies the values of flags kept for compatibility
+// to the flags replacing them, the replacement wins if both are given
+func applyDeprecatedFlags() {
+	deprecated := func(flag, replacement string) {
+		logrus.WithFields(logrus.Fields{
+			"flag":        flag,
+			"replacement": replacement,
+		}).Warn("flag is deprecated")
+	}
+
+	if cfg.PreserveFormatting {
+		deprecated("preserve-formatting", "deepl-preserve-formatting")
+		cfg.DeeplPreserveFormatting = true
+	}
+
+	if cfg.SplitSentences != "" {
+		deprecated("split-sentences", "deepl-split-sentences")
+		if cfg.DeeplSplitSentences == "" {
+			cfg.DeeplSplitSentences = cfg.SplitSentences
+		}
+	}
+
+	if cfg.TagHandling != "" {
+		deprecated("tag-handling", "deepl-tag-handling")
+		if cfg.DeeplTagHandling == "" {
+			cfg.DeeplTagHandling = cfg.TagHandling
+		}
+	}
+
+	if cfg.DeeplTagHandling == "" {
+		cfg.DeeplTagHandling = tagHandlingHTML
+	}
+}
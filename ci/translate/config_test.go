@@ -0,0 +1,110 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfigPrecedence(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		config   string
+		env      string
+		flag     string
+		expected int
+	}{
+		{name: "default", expected: 4},
+		{name: "config file", config: "5", expected: 5},
+		{name: "env over config file", config: "5", env: "6", expected: 6},
+		{name: "flag over env", config: "5", env: "6", flag: "7", expected: 7},
+		{name: "flag over config file", config: "5", flag: "7", expected: 7},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var args []string
+
+			if tc.config != "" {
+				filename := filepath.Join(t.TempDir(), "config.yaml")
+				if err := os.WriteFile(filename, []byte("concurrency: "+tc.config+"\nno-translate-keys: [a, b]\n"), 0o600); err != nil {
+					t.Fatalf("writing config file: %s", err)
+				}
+				args = append(args, "--config="+filename)
+			}
+
+			if tc.env != "" {
+				t.Setenv("CONCURRENCY", tc.env)
+			}
+
+			if tc.flag != "" {
+				args = append(args, "--concurrency="+tc.flag)
+			}
+
+			testConfig(t, args...)
+
+			if cfg.Concurrency != tc.expected {
+				t.Errorf("concurrency = %d, want %d", cfg.Concurrency, tc.expected)
+			}
+
+			if tc.config != "" && len(cfg.NoTranslateKeys) != 2 {
+				t.Errorf("list from config file not applied: %q", cfg.NoTranslateKeys)
+			}
+		})
+	}
+}
+
+func TestLoadConfigFileRejectsUnknownSettings(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(filename, []byte(`{"concurency": 5}`), 0o600); err != nil {
+		t.Fatalf("writing config file: %s", err)
+	}
+
+	if _, err := loadConfigFile(filename); err == nil {
+		t.Error("expected error for unknown setting")
+	}
+}
+
+func TestDeprecatedDeeplFlags(t *testing.T) {
+	for _, tc := range []struct {
+		name               string
+		args               []string
+		preserveFormatting bool
+		splitSentences     string
+		tagHandling        string
+	}{
+		{name: "defaults", tagHandling: tagHandlingHTML},
+		{
+			name:               "new flags",
+			args:               []string{"--deepl-preserve-formatting", "--deepl-split-sentences=0", "--deepl-tag-handling=xml"},
+			preserveFormatting: true,
+			splitSentences:     "0",
+			tagHandling:        tagHandlingXML,
+		},
+		{
+			name:               "deprecated flags",
+			args:               []string{"--preserve-formatting", "--split-sentences=nonewlines", "--tag-handling=off"},
+			preserveFormatting: true,
+			splitSentences:     "nonewlines",
+			tagHandling:        tagHandlingOff,
+		},
+		{
+			name:           "new flags win",
+			args:           []string{"--split-sentences=0", "--deepl-split-sentences=1", "--tag-handling=off", "--deepl-tag-handling=xml"},
+			splitSentences: "1",
+			tagHandling:    tagHandlingXML,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			testConfig(t, tc.args...)
+
+			if cfg.DeeplPreserveFormatting != tc.preserveFormatting {
+				t.Errorf("deepl-preserve-formatting = %v, want %v", cfg.DeeplPreserveFormatting, tc.preserveFormatting)
+			}
+			if cfg.DeeplSplitSentences != tc.splitSentences {
+				t.Errorf("deepl-split-sentences = %q, want %q", cfg.DeeplSplitSentences, tc.splitSentences)
+			}
+			if cfg.DeeplTagHandling != tc.tagHandling {
+				t.Errorf("deepl-tag-handling = %q, want %q", cfg.DeeplTagHandling, tc.tagHandling)
+			}
+		})
+	}
+}
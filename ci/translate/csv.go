@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/csv"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	csvColumnKey    = "key"
+	csvColumnSource = "source"
+)
+
+// exportCSV writes all reference strings into a CSV file with one column
+// per target language containing the existing translation
+func exportCSV(tf translationFile, filename string) error {
+	langs := tf.Languages()
+
+	return writeFileAtomic(filename, func(w io.Writer) error {
+		cw := csv.NewWriter(w)
+		if err := cw.Write(append([]string{csvColumnKey, csvColumnSource}, langs...)); err != nil {
+			return errors.Wrap(err, "writing header")
+		}
+
+		for _, key := range tf.ReferenceKeys() {
+			sources := leafStrings(key, tf.Reference.Translations[key])
+			targets := make(map[string]map[string]string, len(langs))
+			for _, lang := range langs {
+				targets[lang] = leafStrings(key, tf.Translations[lang].Translations[key])
+			}
+
+			paths := make([]string, 0, len(sources))
+			for path := range sources {
+				paths = append(paths, path)
+			}
+			sort.Strings(paths)
+
+			for _, path := range paths {
+				record := []string{path, sources[path]}
+				for _, lang := range langs {
+					record = append(record, targets[lang][path])
+				}
+
+				if err := cw.Write(record); err != nil {
+					return errors.Wrap(err, "writing record")
+				}
+			}
+		}
+
+		cw.Flush()
+		return errors.Wrap(cw.Error(), "writing CSV")
+	})
+}
+
+// importCSV reads a CSV file created by exportCSV and stores all values
+// differing from the current translation as reviewed translations. Blank
+// cells are ignored.
+func importCSV(tf *translationFile, filename string) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return errors.Wrap(err, "opening file")
+	}
+	defer f.Close()
+
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return errors.Wrap(err, "reading CSV")
+	}
+
+	if len(records) == 0 || len(records[0]) < 2 || records[0][0] != csvColumnKey || records[0][1] != csvColumnSource {
+		return errors.Errorf("missing header, expected %q and %q as first columns", csvColumnKey, csvColumnSource)
+	}
+
+	var (
+		header   = records[0]
+		current  = make(map[string]map[string]string)
+		tasks    = make(map[string]map[string]translationTask)
+		verbatim = make(map[string]map[string]bool)
+		imported = make(map[string]int)
+	)
+
+	for _, lang := range header[2:] {
+		if tf.Translations[lang] == nil {
+			logrus.WithField("lang", lang).Warn("unknown language column, skipping")
+			continue
+		}
+
+		if tasks[lang], verbatim[lang], err = importTasks(tf, lang); err != nil {
+			return errors.Wrapf(err, "collecting strings for %s", lang)
+		}
+
+		current[lang] = tf.Translations[lang].leafStrings()
+	}
+
+	for _, record := range records[1:] {
+		path := record[0]
+
+		for i := 2; i < len(record) && i < len(header); i++ {
+			lang, value := header[i], record[i]
+			if tasks[lang] == nil || value == "" {
+				continue
+			}
+
+			task, ok := tasks[lang][path]
+			if !ok {
+				logrus.WithField("key", path).Warn("unknown key, skipping")
+				break
+			}
+
+			if verbatim[lang][path] || current[lang][path] == value {
+				continue
+			}
+
+			task.apply(value)
+			tf.Translations[lang].setSourceHash(task.Path, task.Source)
+			tf.Translations[lang].setProvenance(task.Path, provenanceReviewed)
+			imported[lang]++
+		}
+	}
+
+	for _, lang := range header[2:] {
+		if tasks[lang] != nil {
+			logrus.WithFields(logrus.Fields{"lang": lang, "count": imported[lang]}).Info("imported CSV translations")
+		}
+	}
+
+	return nil
+}
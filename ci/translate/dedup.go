@@ -0,0 +1,79 @@
+package main
+
+import (
+	"strings"
+	"sync"
+)
+
+type (
+	// translationCache deduplicates identical requests within a run: the
+	// first request for a key is sent to the translator, concurrent and
+	// later requests for the same key wait for and reuse its result
+	translationCache struct {
+		entries map[string]*translationCacheEntry
+		mu      sync.Mutex
+	}
+
+	translationCacheEntry struct {
+		done  chan struct{}
+		err   error
+		value string
+	}
+)
+
+// dedupCache is reset at the start of every run
+var dedupCache = newTranslationCache()
+
+func newTranslationCache() *translationCache {
+	return &translationCache{entries: make(map[string]*translationCacheEntry)}
+}
+
+// Do returns the result for the key, calling fn only for the first
+// request of the key. hit is true if the result was reused.
+func (c *translationCache) Do(key string, fn func() (string, error)) (value string, hit bool, err error) {
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	if !ok {
+		entry = &translationCacheEntry{done: make(chan struct{})}
+		c.entries[key] = entry
+	}
+	c.mu.Unlock()
+
+	if ok {
+		<-entry.done
+		return entry.value, true, entry.err
+	}
+
+	entry.value, entry.err = fn()
+	close(entry.done)
+
+	if entry.err != nil {
+		// Waiting requests share the error, later ones try again
+		c.mu.Lock()
+		delete(c.entries, key)
+		c.mu.Unlock()
+	}
+
+	return entry.value, false, entry.err
+}
+
+// Reset removes all cached results
+func (c *translationCache) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = make(map[string]*translationCacheEntry)
+}
+
+// translationCacheKey contains everything influencing the result of the
+// request for the given language
+func translationCacheKey(lang string, req translationRequest) string {
+	return strings.Join([]string{
+		lang,
+		req.SourceLang,
+		req.TagHandling,
+		req.GlossaryID,
+		req.Description,
+		req.Text,
+	}, "\x00")
+}
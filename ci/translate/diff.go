@@ -0,0 +1,200 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// translationStrings returns a copy of all translated strings keyed by
+// language and path to compare the state before and after translating
+func translationStrings(tf translationFile) map[string]map[string]string {
+	out := make(map[string]map[string]string, len(tf.Translations))
+	for lang, tm := range tf.Translations {
+		out[lang] = make(map[string]string)
+		for key, value := range tm.Translations {
+			for path, str := range leafStrings(key, value) {
+				out[lang][path] = str
+			}
+		}
+	}
+	return out
+}
+
+const (
+	diffAdded   = '+'
+	diffChanged = '~'
+	diffPending = '?'
+	diffRemoved = '-'
+)
+
+// diffEntry describes the change of a single string
+type diffEntry struct {
+	Kind     byte
+	Path     string
+	Old, New string
+}
+
+// diffStrings compares the strings per language and returns the
+// changes sorted by path, in dry-run mode the strings which would have
+// been translated are listed as pending
+func diffStrings(before, after map[string]map[string]string, pending map[string][]string) map[string][]diffEntry {
+	langs := make(map[string]bool)
+	for _, m := range []map[string]map[string]string{before, after} {
+		for lang := range m {
+			langs[lang] = true
+		}
+	}
+	for lang := range pending {
+		langs[lang] = true
+	}
+
+	out := make(map[string][]diffEntry)
+	for _, lang := range sortedKeys(langs) {
+		isPend := make(map[string]bool)
+		for _, path := range pending[lang] {
+			isPend[path] = true
+		}
+
+		paths := make(map[string]bool)
+		for path := range before[lang] {
+			paths[path] = true
+		}
+		for path := range after[lang] {
+			paths[path] = true
+		}
+		for path := range isPend {
+			paths[path] = true
+		}
+
+		for _, path := range sortedKeys(paths) {
+			old, hadOld := before[lang][path]
+			cur, hasCur := after[lang][path]
+
+			switch {
+			case isPend[path]:
+				out[lang] = append(out[lang], diffEntry{Kind: diffPending, Path: path})
+			case !hadOld && hasCur:
+				out[lang] = append(out[lang], diffEntry{Kind: diffAdded, Path: path, New: cur})
+			case hadOld && !hasCur:
+				out[lang] = append(out[lang], diffEntry{Kind: diffRemoved, Path: path, Old: old})
+			case old != cur:
+				out[lang] = append(out[lang], diffEntry{Kind: diffChanged, Path: path, Old: old, New: cur})
+			}
+		}
+	}
+
+	return out
+}
+
+// writeDiff writes the strings added (+), changed (~) and removed (-)
+// per language, in dry-run mode the strings which would have been
+// translated are listed as pending (?)
+func writeDiff(w io.Writer, before, after map[string]map[string]string, pending map[string][]string) error {
+	var (
+		buf     = new(strings.Builder)
+		changes = diffStrings(before, after, pending)
+	)
+
+	for _, lang := range sortedLanguages(changes) {
+		fmt.Fprintf(buf, "--- %s (%d)\n", lang, len(changes[lang]))
+		for _, e := range changes[lang] {
+			switch e.Kind {
+			case diffPending:
+				fmt.Fprintf(buf, "  ? %s\n", e.Path)
+			case diffAdded:
+				fmt.Fprintf(buf, "  + %s: %s\n", e.Path, strconv.Quote(e.New))
+			case diffRemoved:
+				fmt.Fprintf(buf, "  - %s: %s\n", e.Path, strconv.Quote(e.Old))
+			case diffChanged:
+				fmt.Fprintf(buf, "  ~ %s: %s => %s\n", e.Path, strconv.Quote(e.Old), strconv.Quote(e.New))
+			}
+		}
+	}
+
+	_, err := io.WriteString(w, buf.String())
+	return errors.Wrap(err, "writing diff")
+}
+
+type (
+	// diffReport is written to the diff-report file
+	diffReport struct {
+		Languages map[string]languageDiff `json:"languages"`
+		Totals    diffCounts              `json:"totals"`
+	}
+
+	languageDiff struct {
+		Added   map[string]string        `json:"added,omitempty"`
+		Changed map[string]changedString `json:"changed,omitempty"`
+		Counts  diffCounts               `json:"counts"`
+		Removed map[string]string        `json:"removed,omitempty"`
+	}
+
+	changedString struct {
+		Old string `json:"old"`
+		New string `json:"new"`
+	}
+
+	diffCounts struct {
+		Added   int `json:"added"`
+		Changed int `json:"changed"`
+		Removed int `json:"removed"`
+	}
+)
+
+// writeDiffReport writes the changes per language with their counts as
+// JSON document into the given file (like all files not in dry-run mode)
+func writeDiffReport(filename string, before, after map[string]map[string]string) error {
+	report := diffReport{Languages: make(map[string]languageDiff)}
+
+	for lang, entries := range diffStrings(before, after, nil) {
+		ld := languageDiff{
+			Added:   make(map[string]string),
+			Changed: make(map[string]changedString),
+			Removed: make(map[string]string),
+		}
+
+		for _, e := range entries {
+			switch e.Kind {
+			case diffAdded:
+				ld.Added[e.Path] = e.New
+				ld.Counts.Added++
+			case diffRemoved:
+				ld.Removed[e.Path] = e.Old
+				ld.Counts.Removed++
+			case diffChanged:
+				ld.Changed[e.Path] = changedString{Old: e.Old, New: e.New}
+				ld.Counts.Changed++
+			}
+		}
+
+		report.Totals.Added += ld.Counts.Added
+		report.Totals.Changed += ld.Counts.Changed
+		report.Totals.Removed += ld.Counts.Removed
+		report.Languages[lang] = ld
+	}
+
+	return writeJSONFile(filename, report)
+}
+
+func sortedLanguages(m map[string][]diffEntry) []string {
+	langs := make([]string, 0, len(m))
+	for lang := range m {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"math"
+
+	"github.com/sirupsen/logrus"
+)
+
+type (
+	// costEstimate sums up the source characters about to be sent to
+	// the translators before any request is made. As every string is
+	// billed by its own length the total does not depend on how the
+	// strings are grouped into requests.
+	costEstimate struct {
+		langs []languageEstimate
+	}
+
+	languageEstimate struct {
+		Lang       string
+		Provider   string
+		Characters int64
+		Strings    int
+	}
+)
+
+// Add records the pending tasks of the language
+func (c *costEstimate) Add(lang, provider string, tasks []translationTask) {
+	if len(tasks) == 0 {
+		return
+	}
+
+	c.langs = append(c.langs, languageEstimate{
+		Lang:       lang,
+		Provider:   provider,
+		Characters: taskCharacters(tasks),
+		Strings:    len(tasks),
+	})
+}
+
+// Log prints the estimated characters per language and in total. The
+// cost is estimated for languages translated through DeepL if a price
+// is configured.
+func (c costEstimate) Log() {
+	if len(c.langs) == 0 {
+		return
+	}
+
+	var (
+		chars, deeplChars int64
+		strs              int
+	)
+
+	for _, le := range c.langs {
+		fields := logrus.Fields{
+			"lang":       le.Lang,
+			"provider":   le.Provider,
+			"strings":    le.Strings,
+			"characters": le.Characters,
+		}
+		if le.Provider == "deepl" && cfg.DeeplPricePerMillion > 0 {
+			fields["cost"] = estimatedCost(le.Characters)
+		}
+		logrus.WithFields(fields).Info("estimated characters to translate")
+
+		chars += le.Characters
+		strs += le.Strings
+		if le.Provider == "deepl" {
+			deeplChars += le.Characters
+		}
+	}
+
+	fields := logrus.Fields{
+		"strings":    strs,
+		"characters": chars,
+	}
+	if cfg.DeeplPricePerMillion > 0 {
+		fields["cost"] = estimatedCost(deeplChars)
+	}
+	logrus.WithFields(fields).Info("estimated characters to translate (all languages)")
+}
+
+// estimatedCost calculates the DeepL price for the given characters,
+// rounded to four decimals to keep the cost of small runs visible
+func estimatedCost(chars int64) float64 {
+	const precision = 1e4
+	return math.Round(float64(chars)/1e6*cfg.DeeplPricePerMillion*precision) / precision
+}
@@ -0,0 +1,110 @@
+package main
+
+import "github.com/sirupsen/logrus"
+
+// withFallbacks returns a copy of the translation file for rendering in
+// which strings missing in a language are filled from the fallback
+// language and then from the reference. The translations themselves are
+// not modified so the fallbacks never end up in the translation file.
+func withFallbacks(tf translationFile) translationFile {
+	var sources []*translationMapping
+	if fb := tf.Translations[cfg.FallbackLanguage]; fb != nil && cfg.FallbackLanguage != tf.Reference.LanguageKey {
+		sources = append(sources, fb)
+	}
+	sources = append(sources, &tf.Reference)
+
+	out := tf
+	out.Translations = make(map[string]*translationMapping, len(tf.Translations))
+
+	for lang, tm := range tf.Translations {
+		// Compare by language: tf is a copy, its Reference is not the one
+		// the translations point to
+		if lang == tf.Reference.LanguageKey {
+			out.Translations[lang] = tm
+			continue
+		}
+
+		filled := *tm
+		filled.Translations = make(translation, len(tf.Reference.Translations))
+
+		var count int
+		for _, key := range tf.ReferenceKeys() {
+			value := tm.Translations[key]
+			for _, src := range sources {
+				if src != tm {
+					value = mergeFallback(value, src.Translations[key], &count)
+				}
+			}
+
+			if value != nil {
+				filled.Translations[key] = value
+			}
+		}
+
+		// Keep keys not (or no longer) present in the reference
+		for key, value := range tm.Translations {
+			if _, ok := filled.Translations[key]; !ok {
+				filled.Translations[key] = value
+			}
+		}
+
+		if count > 0 {
+			logrus.WithFields(logrus.Fields{"lang": lang, "count": count}).Debug("filled missing strings from fallback")
+		}
+
+		out.Translations[lang] = &filled
+	}
+
+	return out
+}
+
+// mergeFallback fills the parts missing in the value from the fallback
+// value without modifying either of them, count is increased for every
+// value taken from the fallback
+func mergeFallback(value, fallback any, count *int) any {
+	value, fallback = normalizeValue(value), normalizeValue(fallback)
+
+	if value == nil {
+		if fallback != nil {
+			*count++
+		}
+		return fallback
+	}
+
+	switch v := value.(type) {
+	case []any:
+		f, ok := fallback.([]any)
+		if !ok {
+			return value
+		}
+
+		merged := append([]any(nil), v...)
+		for i := range f {
+			if i < len(merged) {
+				merged[i] = mergeFallback(merged[i], f[i], count)
+			} else {
+				merged = append(merged, f[i])
+				*count++
+			}
+		}
+		return merged
+
+	case map[string]any:
+		f, ok := fallback.(map[string]any)
+		if !ok {
+			return value
+		}
+
+		merged := make(map[string]any, len(v))
+		for key, elem := range v {
+			merged[key] = elem
+		}
+		for key, elem := range f {
+			merged[key] = mergeFallback(merged[key], elem, count)
+		}
+		return merged
+
+	default:
+		return value
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWithFallbacks(t *testing.T) {
+	testConfig(t, "--fallback", "--fallback-language=de")
+
+	tf := loadTestFile(t, `reference:
+  languageKey: en
+  translations:
+    greeting: Hello
+    menu: [Home, About]
+    nested:
+      title: Title
+translations:
+  de:
+    translations:
+      greeting: Hallo
+      menu: [Start, Über, Hilfe]
+      nested:
+        title: Titel
+        text: Text
+  fr:
+    translations:
+      menu: [Accueil]
+`)
+	tf.Translations[tf.Reference.LanguageKey] = &tf.Reference
+
+	out := withFallbacks(tf)
+
+	for _, tc := range []struct {
+		lang     string
+		expected translation
+	}{
+		{
+			lang: "en",
+			expected: translation{
+				"greeting": "Hello",
+				"menu":     []any{"Home", "About"},
+				"nested":   translation{"title": "Title"},
+			},
+		},
+		{
+			lang: "de",
+			expected: translation{
+				"greeting": "Hallo",
+				"menu":     []any{"Start", "Über", "Hilfe"},
+				"nested":   map[string]any{"title": "Titel", "text": "Text"},
+			},
+		},
+		{
+			lang: "fr",
+			expected: translation{
+				"greeting": "Hallo",
+				"menu":     []any{"Accueil", "Über", "Hilfe"},
+				"nested":   map[string]any{"title": "Titel", "text": "Text"},
+			},
+		},
+	} {
+		if got := out.Translations[tc.lang].Translations; !reflect.DeepEqual(got, tc.expected) {
+			t.Errorf("%s: got %#v, want %#v", tc.lang, got, tc.expected)
+		}
+	}
+
+	if _, ok := tf.Translations["fr"].Translations["greeting"]; ok {
+		t.Error("fallback was written into the translations")
+	}
+}
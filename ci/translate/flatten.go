@@ -0,0 +1,69 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// flattened returns a copy of the translation file for rendering in
+// which nested maps and lists are collapsed into dotted keys, lists
+// using the index of the element ("menu.0"). The reference is flattened
+// as well so its keys (used for the ts TranslationKey type) match the
+// rendered keys. The translation file itself keeps the nested structure.
+func flattened(tf translationFile) translationFile {
+	out := tf
+	out.Reference = flattenMapping(tf.Reference.LanguageKey, &tf.Reference)
+	out.Translations = make(map[string]*translationMapping, len(tf.Translations))
+
+	for lang, tm := range tf.Translations {
+		if lang == tf.Reference.LanguageKey {
+			out.Translations[lang] = &out.Reference
+			continue
+		}
+
+		flat := flattenMapping(lang, tm)
+		out.Translations[lang] = &flat
+	}
+
+	return out
+}
+
+// flattenMapping returns a copy of the mapping with flattened
+// translations
+func flattenMapping(lang string, tm *translationMapping) translationMapping {
+	flat := *tm
+	flat.Translations = make(translation, len(tm.Translations))
+
+	for key, value := range tm.Translations {
+		flattenValue(lang, key, value, flat.Translations)
+	}
+
+	return flat
+}
+
+// flattenValue stores all scalar values contained in the value into the
+// target keyed by their dotted path
+func flattenValue(lang, path string, value any, target translation) {
+	switch v := normalizeValue(value).(type) {
+	case []any:
+		for i, elem := range v {
+			flattenValue(lang, strings.Join([]string{path, strconv.Itoa(i)}, "."), elem, target)
+		}
+
+	case map[string]any:
+		for key, elem := range v {
+			flattenValue(lang, strings.Join([]string{path, key}, "."), elem, target)
+		}
+
+	default:
+		if _, ok := target[path]; ok {
+			logrus.WithFields(logrus.Fields{
+				"lang": lang,
+				"key":  path,
+			}).Warn("flattened key collides with existing key")
+		}
+		target[path] = v
+	}
+}
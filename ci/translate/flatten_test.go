@@ -0,0 +1,56 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFlattened(t *testing.T) {
+	testConfig(t)
+
+	tf := loadTestFile(t, `reference:
+  languageKey: en
+  translations:
+    greeting: Hello
+    menu: [Home, About]
+    nested:
+      title: Hi {name}
+translations:
+  de:
+    translations:
+      greeting: Hallo
+      menu: [Start]
+      nested:
+        title: Hallo
+`)
+	tf.Translations[tf.Reference.LanguageKey] = &tf.Reference
+
+	out := flattened(tf)
+
+	for _, tc := range []struct {
+		lang     string
+		expected translation
+	}{
+		{"en", translation{"greeting": "Hello", "menu.0": "Home", "menu.1": "About", "nested.title": "Hi {name}"}},
+		{"de", translation{"greeting": "Hallo", "menu.0": "Start", "nested.title": "Hallo"}},
+	} {
+		if got := out.Translations[tc.lang].Translations; !reflect.DeepEqual(got, tc.expected) {
+			t.Errorf("%s: got %#v, want %#v", tc.lang, got, tc.expected)
+		}
+	}
+
+	if keys, expected := out.ReferenceKeys(), []string{"greeting", "menu.0", "menu.1", "nested.title"}; !reflect.DeepEqual(keys, expected) {
+		t.Errorf("reference keys = %q, want %q", keys, expected)
+	}
+
+	if _, ok := tf.Reference.Translations["nested"]; !ok {
+		t.Error("flattening modified the translation file")
+	}
+
+	// Both shapes find the missing placeholder of the nested string
+	delete(tf.Translations, "en")
+	delete(out.Translations, "en")
+	if n, m := validatePlaceholders(tf), validatePlaceholders(out); n != 1 || m != 1 {
+		t.Errorf("placeholder mismatches = %d nested, %d flattened, want 1", n, m)
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// defaultReferenceLanguage is used as reference of new translation
+// files if no reference-language is given
+const defaultReferenceLanguage = "en"
+
+// initTranslationFile creates a new translation file from the keys
+// listed in the given file (one per line, - for stdin) with empty
+// reference strings and an empty mapping for every language given in
+// the languages flag. Existing files are never overwritten.
+func initTranslationFile(ctx context.Context, keysFile string) error {
+	if cfg.TranslationFile == stdioFilename {
+		return errors.New("translation-file must be a file to create")
+	}
+
+	if _, err := os.Stat(cfg.TranslationFile); err == nil {
+		return errors.Errorf("translation file %q already exists", cfg.TranslationFile)
+	} else if !os.IsNotExist(err) {
+		return errors.Wrap(err, "checking translation file")
+	}
+
+	keys, err := readKeyList(keysFile)
+	if err != nil {
+		return errors.Wrap(err, "reading keys")
+	}
+
+	refLang := cfg.ReferenceLanguage
+	if refLang == "" {
+		refLang = defaultReferenceLanguage
+	}
+
+	tf := translationFile{
+		Reference: translationMapping{
+			DeeplLanguage: strings.ToUpper(strings.SplitN(strings.ReplaceAll(refLang, "_", "-"), "-", 2)[0]),
+			LanguageKey:   refLang,
+			Translations:  make(translation, len(keys)),
+		},
+	}
+
+	for _, key := range keys {
+		tf.Reference.Translations[key] = ""
+	}
+
+	for _, lang := range cfg.Languages {
+		if err = addLanguage(ctx, &tf, lang); err != nil {
+			return errors.Wrapf(err, "adding language %s", lang)
+		}
+	}
+
+	if tf.Translations == nil {
+		tf.Translations = make(map[string]*translationMapping)
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"keys":      len(keys),
+		"languages": len(tf.Translations),
+		"reference": refLang,
+	}).Info("created translation file")
+
+	return saveTranslationFile(tf)
+}
+
+// readKeyList reads one key per line, skipping empty lines, comments
+// starting with # and duplicates
+func readKeyList(filename string) ([]string, error) {
+	var r io.Reader = os.Stdin
+	if filename != stdioFilename {
+		f, err := os.Open(filename)
+		if err != nil {
+			return nil, errors.Wrap(err, "opening file")
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var (
+		keys    []string
+		seen    = make(map[string]bool)
+		scanner = bufio.NewScanner(r)
+	)
+
+	for scanner.Scan() {
+		key := strings.TrimSpace(scanner.Text())
+		if key == "" || strings.HasPrefix(key, "#") || seen[key] {
+			continue
+		}
+
+		seen[key] = true
+		keys = append(keys, key)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "reading lines")
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("no keys given")
+	}
+
+	return keys, nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBackupTranslationFile(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		save   func(t *testing.T)
+		backup string
+	}{
+		{
+			name: "init without existing file",
+			save: func(t *testing.T) {
+				keysFile := filepath.Join(t.TempDir(), "keys.txt")
+				if err := os.WriteFile(keysFile, []byte("greeting\nfarewell\n"), 0o600); err != nil {
+					t.Fatalf("writing keys: %s", err)
+				}
+
+				if err := initTranslationFile(context.Background(), keysFile); err != nil {
+					t.Fatalf("initializing: %s", err)
+				}
+			},
+		},
+		{
+			name: "save over existing file",
+			save: func(t *testing.T) {
+				tf := loadTestFile(t, testTranslationYAML)
+				tf.Reference.Translations["greeting"] = "Hi {name}"
+
+				if err := saveTranslationFile(tf); err != nil {
+					t.Fatalf("saving: %s", err)
+				}
+			},
+			backup: testTranslationYAML,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			testConfig(t, "--backup")
+
+			tc.save(t)
+
+			if _, err := os.Stat(cfg.TranslationFile); err != nil {
+				t.Fatalf("translation file not written: %s", err)
+			}
+
+			backup, err := os.ReadFile(cfg.TranslationFile + ".bak")
+			switch {
+			case tc.backup == "" && !os.IsNotExist(err):
+				t.Errorf("unexpected backup (err %v): %s", err, backup)
+			case tc.backup != "" && string(backup) != tc.backup:
+				t.Errorf("backup = %q (err %v), want original file", backup, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// addLanguage inserts an empty target language with the given language
+// key. The DeepL language code is derived from the key and checked
+// against the target languages supported by DeepL if an API key is set.
+func addLanguage(ctx context.Context, tf *translationFile, key string) error {
+	key = normalizeLanguageKey(key)
+
+	if key == tf.Reference.LanguageKey {
+		return errors.Errorf("language %q is the reference language", key)
+	}
+
+	for lang, tm := range tf.Translations {
+		if lang == key || (tm != nil && tm.LanguageKey == key) {
+			return errors.Errorf("language %q already exists", key)
+		}
+	}
+
+	code := strings.ToUpper(strings.ReplaceAll(key, "_", "-"))
+
+	deepl := newDeeplTranslator(httpClient, cfg.DeeplAPIEndpoint, cfg.DeeplAPIKey, deeplOptions{})
+
+	supported, err := deepl.(targetLanguageLister).TargetLanguages(ctx)
+	if err != nil {
+		return errors.Wrap(err, "fetching target languages")
+	}
+
+	if supported == nil {
+		logrus.WithField("deepl_language", code).Warn("missing DeepL API key, language code not validated")
+	} else if code, err = matchTargetLanguage(code, supported); err != nil {
+		return err
+	}
+
+	if tf.Translations == nil {
+		tf.Translations = make(map[string]*translationMapping)
+	}
+
+	tf.Translations[key] = &translationMapping{
+		DeeplLanguage: code,
+		LanguageKey:   key,
+		Translations:  translation{},
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"lang":           key,
+		"deepl_language": code,
+	}).Info("added language")
+
+	return nil
+}
+
+// matchTargetLanguage returns the supported code matching the given
+// code. A base language (like "PT") matches if exactly one variant is
+// supported, otherwise the variants are listed in the error.
+func matchTargetLanguage(code string, supported []string) (string, error) {
+	var variants []string
+	for _, s := range supported {
+		switch {
+		case strings.EqualFold(s, code):
+			return strings.ToUpper(s), nil
+		case strings.HasPrefix(strings.ToUpper(s), code+"-"):
+			variants = append(variants, strings.ToUpper(s))
+		}
+	}
+
+	switch len(variants) {
+	case 0:
+		return "", errors.Errorf("language %q is not supported by DeepL", code)
+	case 1:
+		return variants[0], nil
+	default:
+		return "", errors.Errorf("language %q is ambiguous, use one of: %s", code, strings.Join(variants, ", "))
+	}
+}
+
+const (
+	langKeyStyleBCP47      = "bcp47"
+	langKeyStyleKeep       = "keep"
+	langKeyStyleUnderscore = "underscore"
+)
+
+// compactLanguageKeyRegex matches keys without separator like "enUS"
+var compactLanguageKeyRegex = regexp.MustCompile(`^([a-z]{2,3})([A-Z]{2}|[0-9]{3})$`)
+
+// isSelectedLanguage checks whether the language is to be translated
+// in this run: all languages are if the languages flag is not set
+func isSelectedLanguage(lang string) bool {
+	if len(cfg.Languages) == 0 {
+		return true
+	}
+
+	for _, l := range cfg.Languages {
+		if l == lang {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeLanguageKey brings the language key into the form selected
+// by the lang-key-style flag: "en_us", "en-US" and "enUS" all become
+// "en-US" (bcp47) or "en_US" (underscore). Script subtags are title
+// cased ("zh-Hant"), region subtags upper cased, everything else lower
+// cased.
+func normalizeLanguageKey(key string) string {
+	if cfg.LangKeyStyle == langKeyStyleKeep || key == "" {
+		return key
+	}
+
+	if m := compactLanguageKeyRegex.FindStringSubmatch(key); m != nil {
+		key = m[1] + "-" + m[2]
+	}
+
+	subtags := strings.FieldsFunc(key, func(r rune) bool { return r == '-' || r == '_' })
+	for i, tag := range subtags {
+		switch {
+		case i == 0:
+			subtags[i] = strings.ToLower(tag)
+		case len(tag) == 2 || (len(tag) == 3 && strings.Trim(tag, "0123456789") == ""):
+			subtags[i] = strings.ToUpper(tag)
+		case len(tag) == 4:
+			subtags[i] = strings.ToUpper(tag[:1]) + strings.ToLower(tag[1:])
+		default:
+			subtags[i] = strings.ToLower(tag)
+		}
+	}
+
+	sep := "-"
+	if cfg.LangKeyStyle == langKeyStyleUnderscore {
+		sep = "_"
+	}
+
+	return strings.Join(subtags, sep)
+}
+
+// normalizeLanguageKeys rewrites all language keys of the translation
+// file (map keys, languageKey fields and references to languages) into
+// their normalized form. Keys collapsing into the same normalized key
+// are reported as error.
+func normalizeLanguageKeys(tf *translationFile) error {
+	tf.Reference.LanguageKey = normalizeLanguageKey(tf.Reference.LanguageKey)
+
+	var (
+		normalized = make(map[string]*translationMapping, len(tf.Translations))
+		origin     = make(map[string]string, len(tf.Translations))
+		dups       []string
+	)
+
+	for _, lang := range tf.Languages() {
+		tm := tf.Translations[lang]
+		key := normalizeLanguageKey(lang)
+
+		if other, ok := origin[key]; ok {
+			dups = append(dups, fmt.Sprintf("%s and %s (%s)", other, lang, key))
+			continue
+		}
+
+		if tm != nil {
+			tm.LanguageKey = normalizeLanguageKey(tm.LanguageKey)
+		}
+
+		if key != lang {
+			logrus.WithFields(logrus.Fields{"lang": lang, "normalized": key}).Debug("normalized language key")
+		}
+
+		normalized[key], origin[key] = tm, lang
+	}
+
+	if len(dups) > 0 {
+		sort.Strings(dups)
+		return errors.Errorf("duplicate languages after normalizing keys: %s", strings.Join(dups, ", "))
+	}
+
+	if tf.Translations != nil {
+		tf.Translations = normalized
+	}
+
+	for i, ref := range tf.FallbackReferences {
+		tf.FallbackReferences[i] = normalizeLanguageKey(ref)
+	}
+
+	return nil
+}
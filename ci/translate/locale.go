@@ -0,0 +1,61 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+const (
+	textDirLTR = "ltr"
+	textDirRTL = "rtl"
+)
+
+// localeInfo contains the presentation data of a language rendered
+// next to the translations
+type localeInfo struct {
+	BCP47 string
+	Dir   string
+}
+
+var (
+	bcp47Regex = regexp.MustCompile(`^[A-Za-z]{2,8}(-[A-Za-z0-9]{1,8})*$`)
+
+	// rtlLanguages contains the primary language subtags of languages
+	// written right-to-left
+	rtlLanguages = map[string]bool{
+		"ar": true, "arc": true, "ckb": true, "dv": true, "fa": true, "he": true,
+		"iw": true, "ps": true, "sd": true, "ug": true, "ur": true, "yi": true,
+	}
+)
+
+// Locale returns the BCP-47 tag and text direction of the given
+// language. The tag defaults to the language key with underscores
+// replaced, the direction is inferred from the tag if not configured.
+func (t translationFile) Locale(lang string) localeInfo {
+	tm := t.Translations[lang]
+	if lang == t.Reference.LanguageKey {
+		tm = &t.Reference
+	}
+
+	info := localeInfo{BCP47: strings.ReplaceAll(lang, "_", "-")}
+	if tm == nil {
+		return info
+	}
+
+	if tm.LanguageKey != "" {
+		info.BCP47 = strings.ReplaceAll(tm.LanguageKey, "_", "-")
+	}
+	if tm.BCP47 != "" {
+		info.BCP47 = tm.BCP47
+	}
+
+	info.Dir = tm.Dir
+	if info.Dir == "" {
+		info.Dir = textDirLTR
+		if rtlLanguages[strings.ToLower(strings.SplitN(info.BCP47, "-", 2)[0])] {
+			info.Dir = textDirRTL
+		}
+	}
+
+	return info
+}
@@ -0,0 +1,38 @@
+package main
+
+import "sync"
+
+// mappingLocks contains one lock per translation mapping guarding the
+// writes into it. The locks are kept outside of the mapping as mappings
+// are decoded from and copied as values.
+var mappingLocks = struct {
+	mu    sync.Mutex
+	locks map[*translationMapping]*sync.Mutex
+}{locks: make(map[*translationMapping]*sync.Mutex)}
+
+// locked runs fn while holding the lock of the mapping, creating the
+// translations map if required. All writes into a mapping while tasks
+// are processed concurrently must happen within fn.
+func (t *translationMapping) locked(fn func()) {
+	mappingLocks.mu.Lock()
+	mu, ok := mappingLocks.locks[t]
+	if !ok {
+		mu = new(sync.Mutex)
+		mappingLocks.locks[t] = mu
+	}
+	mappingLocks.mu.Unlock()
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if t.Translations == nil {
+		t.Translations = make(translation)
+	}
+	fn()
+}
+
+// setTranslation stores the value for the key while holding the lock
+// of the mapping
+func (t *translationMapping) setTranslation(key string, value any) {
+	t.locked(func() { t.Translations[key] = value })
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestConcurrentStoreTranslation(t *testing.T) {
+	testConfig(t)
+
+	var src strings.Builder
+	src.WriteString("reference:\n  languageKey: en\n  translations:\n")
+	for i := 0; i < 50; i++ {
+		fmt.Fprintf(&src, "    key%02d: Text %d\n", i, i)
+	}
+	src.WriteString("    list: [One, Two, Three, Four]\n    nested:\n      a: A\n      b: B\n")
+	src.WriteString("translations:\n  de:\n    languageKey: de\n")
+
+	tf := loadTestFile(t, src.String())
+
+	tasks, err := pendingTranslations(&tf, "de")
+	if err != nil {
+		t.Fatalf("collecting tasks: %s", err)
+	}
+	if len(tasks) != 56 {
+		t.Fatalf("expected 56 tasks, got %d", len(tasks))
+	}
+
+	var wg sync.WaitGroup
+	for _, task := range tasks {
+		wg.Add(1)
+		go func(task translationTask) {
+			defer wg.Done()
+			storeTranslation(&tf, task, "DE:"+task.Source)
+		}(task)
+	}
+	wg.Wait()
+
+	de := tf.Translations["de"]
+	if stored := de.leafStrings(); len(stored) != len(tasks) {
+		t.Errorf("expected %d strings, got %d", len(tasks), len(stored))
+	}
+	for path, value := range de.leafStrings() {
+		if expected := "DE:" + tf.Reference.leafStrings()[path]; value != expected {
+			t.Errorf("%s = %q, want %q", path, value, expected)
+		}
+	}
+
+	for _, task := range tasks {
+		if _, ok := de.Meta.SourceHashes[task.Path]; !ok {
+			t.Errorf("missing fingerprint for %s", task.Path)
+		}
+		if de.isReviewed(task.Path) {
+			t.Errorf("%s marked reviewed", task.Path)
+		}
+	}
+
+	if got := summary.Report().Languages["de"].Translated; got != len(tasks) {
+		t.Errorf("translated = %d, want %d", got, len(tasks))
+	}
+}
@@ -0,0 +1,404 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const jsTemplate = `// Auto-Generated, do not edit!
+{{ outputHashComment }}
+
+{{ if exportName }}export const {{ exportName }} ={{ else }}export default{{ end }} {
+{{- range $lang := .Languages }}
+  '{{ $lang }}': JSON.parse('{{ (index $.Translations $lang).Translations.ToJSON }}'),
+{{- end }}
+}
+
+export const locales = {
+{{- range $lang := .Languages }}
+  {{- $l := $.Locale $lang }}
+  '{{ $lang }}': { bcp47: '{{ $l.BCP47 }}', dir: '{{ $l.Dir }}' },
+{{- end }}
+}
+`
+
+const tsTemplate = `// Auto-Generated, do not edit!
+{{ outputHashComment }}
+
+export type Locale =
+{{- range $lang := .Languages }}
+  | '{{ $lang }}'
+{{- end }}
+
+export type TranslationKey =
+{{- range $key := .ReferenceKeys }}
+  | '{{ $key }}'
+{{- end }}
+
+{{- if .HasNestedValues }}
+
+export type TranslationValue = string | number | boolean | TranslationValue[] | { [key: string]: TranslationValue }
+{{- end }}
+
+const translations: Record<Locale, Record<TranslationKey, {{ if .HasNestedValues }}TranslationValue{{ else }}string{{ end }}>> = {
+{{- range $lang := .Languages }}
+  '{{ $lang }}': JSON.parse('{{ (index $.Translations $lang).Translations.ToJSON }}'),
+{{- end }}
+}
+
+export const locales: Record<Locale, { bcp47: string, dir: 'ltr' | 'rtl' }> = {
+{{- range $lang := .Languages }}
+  {{- $l := $.Locale $lang }}
+  '{{ $lang }}': { bcp47: '{{ $l.BCP47 }}', dir: '{{ $l.Dir }}' },
+{{- end }}
+}
+
+{{ if exportName -}}
+export { translations as {{ exportName }} }
+{{- else -}}
+export default translations
+{{- end }}
+`
+
+const goTemplate = `// Code generated by ci/translate. DO NOT EDIT.
+{{ outputHashComment }}
+
+package {{ goPackage }}
+
+// Translations contains the translations keyed by language and key
+var Translations = map[string]map[string]any{
+{{- range $lang := .Languages }}
+	{{ printf "%q" $lang }}: {{ goLiteral (index $.Translations $lang).Translations }},
+{{- end }}
+}
+`
+
+// exportNameRegex matches valid JavaScript identifiers to be used as
+// export name
+var exportNameRegex = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// customTemplate contains the template loaded from the template-file
+// and replaces the built-in js / ts template if set
+var customTemplate string
+
+var outputRenderers = map[string]func(translationFile) error{
+	"go":   renderGoFile,
+	"js":   renderJSFile,
+	"json": renderJSONFile,
+	"ts":   renderTSFile,
+}
+
+// renderGoFile renders the translations as Go source and formats it
+// before writing in order to produce compilable, gofmt-clean code
+func renderGoFile(tf translationFile) error {
+	hash, err := outputHash(tf)
+	if err != nil {
+		return err
+	}
+
+	if outputUpToDate(cfg.OutputFile, hash) {
+		logrus.WithField("file", cfg.OutputFile).Info("output up to date")
+		return nil
+	}
+
+	tpl, err := template.New("go").Funcs(template.FuncMap{
+		"goLiteral":         goLiteral,
+		"goPackage":         func() string { return cfg.GoPackage },
+		"outputHashComment": func() string { return outputHashPrefix + hash },
+	}).Parse(goTemplate)
+	if err != nil {
+		return errors.Wrap(err, "parsing template")
+	}
+
+	buf := new(bytes.Buffer)
+	if err = tpl.Execute(buf, tf); err != nil {
+		return errors.Wrap(err, "rendering go template")
+	}
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return errors.Wrap(err, "formatting go source")
+	}
+
+	return writeFileAtomic(cfg.OutputFile, func(w io.Writer) error {
+		_, err := w.Write(src)
+		return errors.Wrap(err, "writing go source")
+	})
+}
+
+// goLiteral converts a translation value into a Go composite literal
+// with map keys in sorted order
+func goLiteral(value any) string {
+	switch v := normalizeValue(value).(type) {
+	case string:
+		return strconv.Quote(v)
+
+	case []any:
+		elems := make([]string, len(v))
+		for i := range v {
+			elems[i] = goLiteral(v[i])
+		}
+		return goCompositeLiteral("[]any", elems)
+
+	case map[string]any:
+		elems := make([]string, 0, len(v))
+		for _, key := range sortedMapKeys(v) {
+			elems = append(elems, strconv.Quote(key)+": "+goLiteral(v[key]))
+		}
+		return goCompositeLiteral("map[string]any", elems)
+
+	case nil:
+		return "nil"
+
+	default:
+		return fmt.Sprintf("%#v", v)
+	}
+}
+
+// goCompositeLiteral puts every element on its own line so gofmt can
+// indent nested structures in a readable way
+func goCompositeLiteral(typ string, elems []string) string {
+	if len(elems) == 0 {
+		return typ + "{}"
+	}
+	return typ + "{\n" + strings.Join(elems, ",\n") + ",\n}"
+}
+
+func renderJSFile(tf translationFile) error {
+	return renderTemplateFile(tf, "js", templateSource(jsTemplate))
+}
+
+func renderTSFile(tf translationFile) error {
+	return renderTemplateFile(tf, "ts", templateSource(tsTemplate))
+}
+
+// templateSource returns the custom template if one was loaded and the
+// given built-in template otherwise
+func templateSource(builtin string) string {
+	if customTemplate != "" {
+		return customTemplate
+	}
+	return builtin
+}
+
+// loadTemplateFile reads the template from disk and parses it to report
+// syntax errors before any translation is done
+func loadTemplateFile(filename string) (string, error) {
+	src, err := os.ReadFile(filename)
+	if err != nil {
+		return "", errors.Wrap(err, "reading template")
+	}
+
+	if _, err = template.New("custom").Funcs(templateFuncs("")).Parse(string(src)); err != nil {
+		return "", errors.Wrap(err, "parsing template")
+	}
+
+	return string(src), nil
+}
+
+// templateFuncs returns the functions available in the js / ts
+// templates
+func templateFuncs(hash string) template.FuncMap {
+	return template.FuncMap{
+		"exportName":        func() string { return cfg.ExportName },
+		"outputHashComment": func() string { return outputHashPrefix + hash },
+	}
+}
+
+// renderTemplateFile renders the template into the output file unless
+// the file has already been rendered from the same data
+func renderTemplateFile(tf translationFile, name, tplSource string) error {
+	hash, err := outputHash(tf)
+	if err != nil {
+		return err
+	}
+
+	if outputUpToDate(cfg.OutputFile, hash) {
+		logrus.WithField("file", cfg.OutputFile).Info("output up to date")
+		return nil
+	}
+
+	tpl, err := template.New(name).Funcs(templateFuncs(hash)).Parse(tplSource)
+	if err != nil {
+		return errors.Wrap(err, "parsing template")
+	}
+
+	return writeFileAtomic(cfg.OutputFile, func(w io.Writer) error {
+		return renderValidatedTemplate(w, tpl, tf)
+	})
+}
+
+// renderValidatedTemplate renders the template into a buffer and only
+// writes it if the embedded JSON payloads are intact, so a failing
+// validation leaves the existing file in place
+func renderValidatedTemplate(w io.Writer, tpl *template.Template, data any) error {
+	buf := new(bytes.Buffer)
+	if err := tpl.Execute(buf, data); err != nil {
+		return errors.Wrapf(err, "rendering %s template", tpl.Name())
+	}
+
+	if err := validateJSONPayloads(buf.String()); err != nil {
+		return errors.Wrap(err, "validating rendered output")
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return errors.Wrap(err, "writing rendered output")
+}
+
+// validateJSONPayloads checks every JSON.parse('...') call in the
+// rendered source contains a properly terminated string literal holding
+// valid JSON
+func validateJSONPayloads(src string) error {
+	matches := jsonPayloadRegex.FindAllStringSubmatch(src, -1)
+	if calls := strings.Count(src, jsonPayloadPrefix); calls != len(matches) {
+		return errors.Errorf("%d of %d JSON.parse calls are malformed", calls-len(matches), calls)
+	}
+
+	for i, m := range matches {
+		if !json.Valid([]byte(jsStringUnescaper.Replace(m[1]))) {
+			return errors.Errorf("JSON.parse call %d contains invalid JSON", i+1)
+		}
+	}
+
+	return nil
+}
+
+func renderJSONFile(tf translationFile) error {
+	if cfg.OutputDir != "" {
+		for _, lang := range tf.Languages() {
+			if err := writeJSONFile(filepath.Join(cfg.OutputDir, lang+".json"), tf.Translations[lang].Translations); err != nil {
+				return errors.Wrapf(err, "writing language %s", lang)
+			}
+		}
+		return nil
+	}
+
+	out := make(map[string]translation, len(tf.Translations))
+	for lang, tm := range tf.Translations {
+		out[lang] = tm.Translations
+	}
+
+	return writeJSONFile(cfg.OutputFile, out)
+}
+
+func writeJSONFile(filename string, data any) error {
+	return writeFileAtomic(filename, func(w io.Writer) error {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+
+		return errors.Wrap(encoder.Encode(data), "encoding JSON")
+	})
+}
+
+// writeFileAtomic renders the content into a tempfile next to the given
+// filename and moves it in place after the content was written
+func writeFileAtomic(filename string, render func(io.Writer) error) error {
+	if cfg.DryRun {
+		logrus.WithField("file", filename).Info("dry-run: would write file")
+		return nil
+	}
+
+	if filename == stdioFilename {
+		// A pipe cannot be renamed, so write directly
+		return render(os.Stdout)
+	}
+
+	f, err := os.Create(filename + ".tmp")
+	if err != nil {
+		return errors.Wrap(err, "creating tempfile")
+	}
+
+	if err = render(f); err != nil {
+		f.Close()
+		os.Remove(filename + ".tmp")
+		return err
+	}
+
+	f.Close()
+	return errors.Wrap(os.Rename(filename+".tmp", filename), "moving file in place")
+}
+
+// jsStringEscaper escapes JSON for embedding into a single-quoted JS
+// string: backslashes need to be doubled to survive the JS string
+// parsing before JSON.parse sees them. Line terminators cannot occur in
+// marshalled JSON but are escaped anyway as they end a JS string literal.
+var jsStringEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	`'`, `\'`,
+	"\n", `\n`,
+	"\r", `\r`,
+	"\u2028", `\u2028`,
+	"\u2029", `\u2029`,
+)
+
+// jsStringUnescaper reverts jsStringEscaper to extract the JSON from
+// the rendered string literals
+var jsStringUnescaper = strings.NewReplacer(
+	`\\`, `\`,
+	`\'`, `'`,
+	`\n`, "\n",
+	`\r`, "\r",
+	`\u2028`, "\u2028",
+	`\u2029`, "\u2029",
+)
+
+const jsonPayloadPrefix = "JSON.parse('"
+
+// jsonPayloadRegex matches a JSON.parse call with a single-quoted string
+// literal not broken by unescaped quotes or line terminators
+var jsonPayloadRegex = regexp.MustCompile(`JSON\.parse\('((?:[^'\\\n\r]|\\.)*)'\)`)
+
+// ToJSON marshals the translation for embedding into a single-quoted JS
+// string. Map keys are sorted by json.Marshal and lists keep the order
+// of the translation file, so identical input yields identical output.
+func (t translation) ToJSON() (string, error) {
+	j, err := json.Marshal(t)
+	return jsStringEscaper.Replace(string(j)), errors.Wrap(err, "marshalling JSON")
+}
+
+// Languages returns the keys of all translations in sorted order
+func (t translationFile) Languages() []string {
+	var langs []string
+	for lang := range t.Translations {
+		langs = append(langs, lang)
+	}
+
+	sort.Strings(langs)
+	return langs
+}
+
+// ReferenceKeys returns the keys of the reference translation in sorted
+// order
+func (t translationFile) ReferenceKeys() []string {
+	var keys []string
+	for key := range t.Reference.Translations {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+// HasNestedValues reports whether any reference key holds a value other
+// than a string (a list, map, number or boolean)
+func (t translationFile) HasNestedValues() bool {
+	for _, value := range t.Reference.Translations {
+		if _, ok := value.(string); !ok {
+			return true
+		}
+	}
+	return false
+}
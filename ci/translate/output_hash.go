@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// outputHashPrefix marks the comment containing the hash of the
+	// data the output was rendered from
+	outputHashPrefix = "// Source hash: "
+	// outputHashScanLines limits how many lines of the existing output
+	// are searched for the hash comment
+	outputHashScanLines = 5
+)
+
+// outputHash calculates a hash over the data influencing the rendered
+// output including the tool version
+func outputHash(tf translationFile) (string, error) {
+	var (
+		locales      = make(map[string]localeInfo, len(tf.Translations))
+		translations = make(map[string]translation, len(tf.Translations))
+	)
+	for lang, tm := range tf.Translations {
+		locales[lang] = tf.Locale(lang)
+		translations[lang] = tm.Translations
+	}
+
+	data, err := json.Marshal(struct {
+		Version      string
+		Format       string
+		GoPackage    string
+		ExportName   string `json:",omitempty"`
+		Template     string `json:",omitempty"`
+		Locales      map[string]localeInfo
+		Translations map[string]translation
+	}{version, cfg.OutputFormat, cfg.GoPackage, cfg.ExportName, customTemplate, locales, translations})
+	if err != nil {
+		return "", errors.Wrap(err, "marshalling output data")
+	}
+
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:]), nil
+}
+
+// outputUpToDate checks whether the existing output file was rendered
+// from data with the given hash
+func outputUpToDate(filename, hash string) bool {
+	if cfg.ForceRender || filename == stdioFilename {
+		return false
+	}
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for i := 0; i < outputHashScanLines && scanner.Scan(); i++ {
+		if strings.TrimSpace(scanner.Text()) == outputHashPrefix+hash {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestOutputHash(t *testing.T) {
+	testConfig(t)
+
+	base := loadTestFile(t, testTranslationYAML)
+	baseHash, err := outputHash(base)
+	if err != nil {
+		t.Fatalf("hashing: %s", err)
+	}
+
+	for _, tc := range []struct {
+		name    string
+		modify  func(tf *translationFile)
+		changed bool
+	}{
+		{name: "same data", modify: func(*translationFile) {}},
+		{
+			name: "unrelated metadata",
+			modify: func(tf *translationFile) {
+				tf.Translations["de"].setSourceHash("greeting", "other")
+			},
+		},
+		{
+			name:    "changed translation",
+			modify:  func(tf *translationFile) { tf.Translations["de"].Translations["greeting"] = "Servus" },
+			changed: true,
+		},
+		{
+			name:    "added language",
+			modify:  func(tf *translationFile) { tf.Translations["it"] = &translationMapping{} },
+			changed: true,
+		},
+		{
+			name:    "output format",
+			modify:  func(*translationFile) { cfg.OutputFormat = "ts" },
+			changed: true,
+		},
+		{
+			name:    "go package",
+			modify:  func(*translationFile) { cfg.GoPackage = "i18n" },
+			changed: true,
+		},
+		{
+			name:    "export name",
+			modify:  func(*translationFile) { cfg.ExportName = "langs" },
+			changed: true,
+		},
+		{
+			name:    "custom template",
+			modify:  func(*translationFile) { customTemplate = "{{ . }}" },
+			changed: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			savedCfg, savedTemplate := cfg, customTemplate
+			defer func() { cfg, customTemplate = savedCfg, savedTemplate }()
+
+			tf := loadTestFile(t, testTranslationYAML)
+			tc.modify(&tf)
+
+			hash, err := outputHash(tf)
+			if err != nil {
+				t.Fatalf("hashing: %s", err)
+			}
+			if (hash != baseHash) != tc.changed {
+				t.Errorf("hash changed = %v, want %v", hash != baseHash, tc.changed)
+			}
+		})
+	}
+}
+
+func TestRenderSkipsUpToDateOutput(t *testing.T) {
+	for _, format := range []string{"go", "js", "ts"} {
+		t.Run(format, func(t *testing.T) {
+			testConfig(t, "--output-format="+format)
+
+			render := func(tf translationFile) {
+				tf.Translations[tf.Reference.LanguageKey] = &tf.Reference
+				if err := outputRenderers[format](tf); err != nil {
+					t.Fatalf("rendering: %s", err)
+				}
+			}
+
+			tf := loadTestFile(t, testTranslationYAML)
+			render(tf)
+
+			out := readTestFile(t, cfg.OutputFile)
+			if !strings.Contains(out, outputHashPrefix) {
+				t.Fatalf("output does not contain the hash comment:\n%s", out)
+			}
+
+			// A marker shows whether the file has been rewritten
+			const marker = "\n// not rewritten\n"
+			if err := os.WriteFile(cfg.OutputFile, []byte(out+marker), 0o600); err != nil {
+				t.Fatalf("writing marker: %s", err)
+			}
+
+			render(loadTestFile(t, testTranslationYAML))
+			if !strings.HasSuffix(readTestFile(t, cfg.OutputFile), marker) {
+				t.Error("up-to-date output was rewritten")
+			}
+
+			tf = loadTestFile(t, testTranslationYAML)
+			tf.Translations["de"].Translations["greeting"] = "Servus {name}"
+			render(tf)
+			if out = readTestFile(t, cfg.OutputFile); strings.HasSuffix(out, marker) || !strings.Contains(out, "Servus") {
+				t.Errorf("outdated output was not rewritten:\n%s", out)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"text/template"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const jsLanguageTemplate = `// Auto-Generated, do not edit!
+
+export default JSON.parse('{{ .ToJSON }}')
+`
+
+const tsLanguageTemplate = `// Auto-Generated, do not edit!
+
+export type TranslationValue = string | number | boolean | TranslationValue[] | { [key: string]: TranslationValue }
+
+const translations: Record<string, TranslationValue> = JSON.parse('{{ .ToJSON }}')
+
+export default translations
+`
+
+// splitOutputTemplates contains the templates for a single language
+// keyed by output-format, json does not need a template
+var splitOutputTemplates = map[string]string{
+	"js": jsLanguageTemplate,
+	"ts": tsLanguageTemplate,
+}
+
+// splitOutputDir returns the directory the per-language files are
+// written to when splitting the output
+func splitOutputDir() string {
+	return filepath.Join(filepath.Dir(cfg.OutputFile), "langs")
+}
+
+// renderSplitOutput writes one file per language into the split output
+// directory and removes files of languages no longer present
+func renderSplitOutput(tf translationFile) error {
+	var (
+		dir = splitOutputDir()
+		ext = "." + cfg.OutputFormat
+	)
+
+	if !cfg.DryRun {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return errors.Wrap(err, "creating output directory")
+		}
+	}
+
+	var tpl *template.Template
+	if src, ok := splitOutputTemplates[cfg.OutputFormat]; ok {
+		var err error
+		if tpl, err = template.New(cfg.OutputFormat).Parse(src); err != nil {
+			return errors.Wrap(err, "parsing template")
+		}
+	}
+
+	written := make(map[string]bool)
+	for _, lang := range tf.Languages() {
+		filename := filepath.Join(dir, lang+ext)
+		written[filename] = true
+
+		var err error
+		if tpl == nil {
+			err = writeJSONFile(filename, tf.Translations[lang].Translations)
+		} else {
+			err = writeFileAtomic(filename, func(w io.Writer) error {
+				return renderValidatedTemplate(w, tpl, tf.Translations[lang].Translations)
+			})
+		}
+		if err != nil {
+			return errors.Wrapf(err, "writing language %s", lang)
+		}
+	}
+
+	return removeStaleLanguageFiles(dir, ext, written)
+}
+
+// removeStaleLanguageFiles deletes files with the given extension in the
+// directory which have not been written in this run
+func removeStaleLanguageFiles(dir, ext string, written map[string]bool) error {
+	existing, err := filepath.Glob(filepath.Join(dir, "*"+ext))
+	if err != nil {
+		return errors.Wrap(err, "listing output directory")
+	}
+
+	for _, filename := range existing {
+		if written[filename] {
+			continue
+		}
+
+		logger := logrus.WithField("file", filename)
+		if cfg.DryRun {
+			logger.Info("dry-run: would remove stale language file")
+			continue
+		}
+
+		logger.Info("removing stale language file")
+		if err = os.Remove(filename); err != nil {
+			return errors.Wrap(err, "removing stale language file")
+		}
+	}
+
+	return nil
+}
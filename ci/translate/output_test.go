@@ -0,0 +1,230 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRenderIsDeterministic(t *testing.T) {
+	for _, format := range []string{"go", "js", "json", "ts"} {
+		t.Run(format, func(t *testing.T) {
+			testConfig(t, "--output-format="+format, "--force-render")
+
+			var outputs []string
+			for i := 0; i < 2; i++ {
+				tf := loadTestFile(t, testTranslationYAML)
+				tf.Translations[tf.Reference.LanguageKey] = &tf.Reference
+
+				if err := outputRenderers[format](tf); err != nil {
+					t.Fatalf("rendering: %s", err)
+				}
+				outputs = append(outputs, readTestFile(t, cfg.OutputFile))
+			}
+
+			if outputs[0] != outputs[1] {
+				t.Errorf("rendering the same input twice differs:\n%s\n---\n%s", outputs[0], outputs[1])
+			}
+		})
+	}
+}
+
+func TestTSTranslationTypes(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		yaml     string
+		flags    []string
+		expected []string
+		absent   []string
+	}{
+		{
+			name: "flat",
+			yaml: "reference:\n  languageKey: en\n  translations:\n    a: A\n    b: B\ntranslations:\n  de:\n    translations:\n      a: A-de\n",
+			expected: []string{
+				"export type TranslationKey =\n  | 'a'\n  | 'b'\n",
+				"const translations: Record<Locale, Record<TranslationKey, string>> = {",
+			},
+			absent: []string{"TranslationValue"},
+		},
+		{
+			name: "nested",
+			yaml: testTranslationYAML,
+			expected: []string{
+				"export type TranslationValue = ",
+				"const translations: Record<Locale, Record<TranslationKey, TranslationValue>> = {",
+			},
+		},
+		{
+			name:  "flattened",
+			yaml:  testTranslationYAML,
+			flags: []string{"--flatten"},
+			expected: []string{
+				"export type TranslationKey =\n  | 'greeting'\n  | 'menu.0'\n  | 'menu.1'\n  | 'nested.text'\n  | 'nested.title'\n",
+				"const translations: Record<Locale, Record<TranslationKey, string>> = {",
+			},
+			absent: []string{"TranslationValue", "| 'menu'\n", "| 'nested'\n"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			testConfig(t, append([]string{"--output-format=ts"}, tc.flags...)...)
+
+			tf := loadTestFile(t, tc.yaml)
+			tf.Translations[tf.Reference.LanguageKey] = &tf.Reference
+			if cfg.Flatten {
+				tf = flattened(tf)
+			}
+
+			if err := renderTSFile(tf); err != nil {
+				t.Fatalf("rendering: %s", err)
+			}
+
+			out := readTestFile(t, cfg.OutputFile)
+			for _, exp := range tc.expected {
+				if !strings.Contains(out, exp) {
+					t.Errorf("output does not contain %q:\n%s", exp, out)
+				}
+			}
+			for _, unexp := range tc.absent {
+				if strings.Contains(out, unexp) {
+					t.Errorf("output contains %q:\n%s", unexp, out)
+				}
+			}
+		})
+	}
+}
+
+func TestToJSONEscaping(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		value string
+	}{
+		{"backslash", `C:\path\to\file`},
+		{"newline", "first\nsecond"},
+		{"carriage return", "first\r\nsecond"},
+		{"quotes", `It's a "test"`},
+		{"line separators", "a\u2028b\u2029c"},
+		{"escaped quote", `\'`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			escaped, err := translation{"key": tc.value}.ToJSON()
+			if err != nil {
+				t.Fatalf("marshalling: %s", err)
+			}
+
+			for _, c := range []string{"\n", "\r", "\u2028", "\u2029"} {
+				if strings.Contains(escaped, c) {
+					t.Errorf("escaped string contains line terminator %q: %s", c, escaped)
+				}
+			}
+
+			m := jsonPayloadRegex.FindStringSubmatch("JSON.parse('" + escaped + "')")
+			if m == nil {
+				t.Fatalf("escaped string does not form a single JS string literal: %s", escaped)
+			}
+
+			var decoded map[string]string
+			if err = json.Unmarshal([]byte(jsStringUnescaper.Replace(m[1])), &decoded); err != nil {
+				t.Fatalf("decoding JSON from literal: %s", err)
+			}
+			if decoded["key"] != tc.value {
+				t.Errorf("got %q back, want %q", decoded["key"], tc.value)
+			}
+		})
+	}
+}
+
+func TestValidateJSONPayloads(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		src   string
+		valid bool
+	}{
+		{name: "no calls", src: "export default {}", valid: true},
+		{name: "valid", src: `JSON.parse('{"a":"it\'s"}')`, valid: true},
+		{name: "escaped backslash", src: `JSON.parse('{"a":"C:\\\\dir"}')`, valid: true},
+		{name: "multiple", src: "JSON.parse('{}'),\nJSON.parse('[1]')", valid: true},
+		{name: "unescaped quote", src: `JSON.parse('{"a":"it's"}')`},
+		{name: "raw newline", src: "JSON.parse('{\"a\":\"a\nb\"}')"},
+		{name: "invalid JSON", src: `JSON.parse('{"a":}')`},
+		{name: "one of two malformed", src: "JSON.parse('{}'),\nJSON.parse('{'}')"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := validateJSONPayloads(tc.src); (err == nil) != tc.valid {
+				t.Errorf("validateJSONPayloads() = %v, want valid %v", err, tc.valid)
+			}
+		})
+	}
+}
+
+func TestRenderJSFileParses(t *testing.T) {
+	testConfig(t, "--force-render")
+
+	strs := translation{
+		"backslash": `C:\path\to\file`,
+		"newline":   "first\nsecond\r\nthird",
+		"quotes":    `It's a "test" \'`,
+		"unicode":   "Grüße 👋 ✓ a\u2028b\u2029c",
+	}
+
+	tf := translationFile{
+		Reference:    translationMapping{LanguageKey: "en", Translations: strs},
+		Translations: map[string]*translationMapping{},
+	}
+	tf.Translations["en"] = &tf.Reference
+
+	if err := renderJSFile(tf); err != nil {
+		t.Fatalf("rendering: %s", err)
+	}
+
+	src := readTestFile(t, cfg.OutputFile)
+	if err := validateJSONPayloads(src); err != nil {
+		t.Fatalf("rendered file contains invalid payloads: %s\n%s", err, src)
+	}
+
+	node, err := exec.LookPath("node")
+	if err != nil {
+		t.Skip("node not available, skipping evaluation of the rendered file")
+	}
+
+	module := filepath.Join(t.TempDir(), "langs.mjs")
+	if err = os.WriteFile(module, []byte(src), 0o600); err != nil {
+		t.Fatalf("writing module: %s", err)
+	}
+
+	out, err := exec.Command(node, "--input-type=module", "-e",
+		"const m = await import(process.argv[1]); console.log(JSON.stringify(m.default.en))", module).Output()
+	if err != nil {
+		t.Fatalf("evaluating rendered file: %s\n%s", err, src)
+	}
+
+	var got translation
+	if err = json.Unmarshal(out, &got); err != nil {
+		t.Fatalf("decoding node output: %s", err)
+	}
+	if !reflect.DeepEqual(got, strs) {
+		t.Errorf("evaluated translations differ:\n got: %q\nwant: %q", got, strs)
+	}
+}
+
+func TestDefaultOutputFileMatchesFormat(t *testing.T) {
+	for _, tc := range []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"--output-file=" + defaultOutputFile}, "../../src/langs/langs.js"},
+		{[]string{"--output-file=" + defaultOutputFile, "--output-format=json"}, "../../src/langs/langs.json"},
+		{[]string{"--output-file=" + defaultOutputFile, "--output-format=ts"}, "../../src/langs/langs.ts"},
+		{[]string{"--output-file=" + defaultOutputFile, "--output-format=go"}, "../../src/langs/langs.go"},
+		{[]string{"--output-file=custom.js", "--output-format=json"}, "custom.js"},
+	} {
+		testConfig(t, tc.args...)
+
+		if cfg.OutputFile != tc.expected {
+			t.Errorf("%v: expected output-file %q, got %q", tc.args, tc.expected, cfg.OutputFile)
+		}
+	}
+}
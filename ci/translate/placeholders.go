@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// placeholderRegex matches the interpolation tokens, it is compiled
+	// from the placeholder-regex flag in initApp
+	placeholderRegex *regexp.Regexp
+
+	// protectRegex matches all tokens to be kept from the translator
+	// (see protect-placeholders and markdown), it is nil if nothing is
+	// to be protected
+	protectRegex *regexp.Regexp
+
+	protectedPlaceholderRegex = regexp.MustCompile(`<x\s+id\s*=\s*"(\d+)"\s*>(?s:.*?)</x\s*>`)
+)
+
+// protectPlaceholderTag is the tag the interpolation tokens are wrapped
+// in to be ignored by the translator
+const protectPlaceholderTag = "x"
+
+// markdownTokenPattern matches the parts of Markdown strings not to be
+// translated: code blocks, code spans, link targets and URLs. The link
+// text is left to the translator.
+const markdownTokenPattern = "(?s:```.*?```|~~~.*?~~~)" +
+	"|`[^`]+`" +
+	`|\]\([^)\s]*(?:\s+"[^"]*")?\)` +
+	`|<[a-zA-Z][a-zA-Z0-9+.-]*:[^>\s]+>` +
+	`|https?://[^\s<>()\[\]]*[^\s<>()\[\].,;:!?'"]`
+
+// compileProtectRegex combines the patterns of all enabled protections,
+// Markdown constructs take precedence over placeholders contained in
+// them
+func compileProtectRegex(markdown, placeholders bool, placeholderPattern string) (*regexp.Regexp, error) {
+	var patterns []string
+	if markdown {
+		patterns = append(patterns, markdownTokenPattern)
+	}
+	if placeholders {
+		patterns = append(patterns, placeholderPattern)
+	}
+
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+
+	return regexp.Compile("(?:" + strings.Join(patterns, ")|(?:") + ")")
+}
+
+// validatePlaceholders compares the interpolation tokens of every
+// reference string with the tokens of its translations and returns the
+// number of mismatches found
+func validatePlaceholders(tf translationFile) (mismatches int) {
+	for _, lang := range tf.Languages() {
+		for _, key := range tf.ReferenceKeys() {
+			translated := leafStrings(key, tf.Translations[lang].Translations[key])
+
+			for path, src := range leafStrings(key, tf.Reference.Translations[key]) {
+				dest, ok := translated[path]
+				if !ok {
+					continue
+				}
+
+				missing, unexpected := diffPlaceholders(extractPlaceholders(src), extractPlaceholders(dest))
+				if len(missing) == 0 && len(unexpected) == 0 {
+					continue
+				}
+
+				logrus.WithFields(logrus.Fields{
+					"lang":       lang,
+					"key":        path,
+					"missing":    strings.Join(missing, ", "),
+					"unexpected": strings.Join(unexpected, ", "),
+				}).Warn("placeholder mismatch in translation")
+				mismatches++
+			}
+		}
+	}
+
+	return mismatches
+}
+
+func diffPlaceholders(src, dest map[string]bool) (missing, unexpected []string) {
+	for token := range src {
+		if !dest[token] {
+			missing = append(missing, token)
+		}
+	}
+
+	for token := range dest {
+		if !src[token] {
+			unexpected = append(unexpected, token)
+		}
+	}
+
+	sort.Strings(missing)
+	sort.Strings(unexpected)
+	return missing, unexpected
+}
+
+func extractPlaceholders(text string) map[string]bool {
+	tokens := make(map[string]bool)
+	for _, token := range placeholderRegex.FindAllString(text, -1) {
+		tokens[token] = true
+	}
+	return tokens
+}
+
+// protectPlaceholders wraps all tokens matched by the protectRegex into
+// ignore tags and returns the tokens in order of their index given in
+// the tag
+func protectPlaceholders(text string) (string, []string) {
+	var tokens []string
+
+	protected := protectRegex.ReplaceAllStringFunc(text, func(token string) string {
+		// Keep the closing bracket of the link text in place
+		prefix := ""
+		if strings.HasPrefix(token, "](") {
+			prefix, token = "]", token[1:]
+		}
+
+		tokens = append(tokens, token)
+		return fmt.Sprintf(`%[1]s<%[2]s id="%[3]d">%[4]s</%[2]s>`, prefix, protectPlaceholderTag, len(tokens)-1, token)
+	})
+
+	return protected, tokens
+}
+
+// restorePlaceholders replaces the ignore tags created by
+// protectPlaceholders with the original tokens. The content of the tags
+// is discarded so modifications by the translator (like whitespace added
+// inside the tag) do not end up in the translation.
+func restorePlaceholders(text string, tokens []string) string {
+	return protectedPlaceholderRegex.ReplaceAllStringFunc(text, func(tag string) string {
+		idx, err := strconv.Atoi(protectedPlaceholderRegex.FindStringSubmatch(tag)[1])
+		if err != nil || idx >= len(tokens) {
+			return tag
+		}
+		return tokens[idx]
+	})
+}
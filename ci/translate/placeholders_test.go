@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// upperTranslator "translates" by upper-casing everything outside the
+// ignore tags, so text wrongly sent to the translator shows up in caps
+type upperTranslator struct {
+	fakeTranslator
+}
+
+func (u *upperTranslator) Translate(ctx context.Context, req translationRequest) (string, error) {
+	if _, err := u.fakeTranslator.Translate(ctx, req); err != nil {
+		return "", err
+	}
+
+	var (
+		out  strings.Builder
+		last int
+	)
+	for _, loc := range protectedPlaceholderRegex.FindAllStringIndex(req.Text, -1) {
+		out.WriteString(strings.ToUpper(req.Text[last:loc[0]]))
+		out.WriteString(req.Text[loc[0]:loc[1]])
+		last = loc[1]
+	}
+	out.WriteString(strings.ToUpper(req.Text[last:]))
+
+	return out.String(), nil
+}
+
+func TestMarkdownProtection(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		flags    []string
+		text     string
+		sent     string
+		expected string
+	}{
+		{
+			name:     "inline code",
+			flags:    []string{"--markdown"},
+			text:     "Set `config` first",
+			sent:     "Set <x id=\"0\">`config`</x> first",
+			expected: "SET `config` FIRST",
+		},
+		{
+			name:     "code block",
+			flags:    []string{"--markdown"},
+			text:     "Run\n```\nmake all\n```",
+			sent:     "Run\n<x id=\"0\">```\nmake all\n```</x>",
+			expected: "RUN\n```\nmake all\n```",
+		},
+		{
+			name:     "link",
+			flags:    []string{"--markdown"},
+			text:     "See [the docs](/help) for `config`",
+			sent:     "See [the docs]<x id=\"0\">(/help)</x> for <x id=\"1\">`config`</x>",
+			expected: "SEE [THE DOCS](/help) FOR `config`",
+		},
+		{
+			name:     "link with title",
+			flags:    []string{"--markdown"},
+			text:     `[docs](/help "Help")`,
+			sent:     `[docs]<x id="0">(/help "Help")</x>`,
+			expected: `[DOCS](/help "Help")`,
+		},
+		{
+			name:     "bare URL",
+			flags:    []string{"--markdown"},
+			text:     "Go to https://example.com/a?b=c.",
+			sent:     "Go to <x id=\"0\">https://example.com/a?b=c</x>.",
+			expected: "GO TO https://example.com/a?b=c.",
+		},
+		{
+			name:     "autolink",
+			flags:    []string{"--markdown"},
+			text:     "Mail <mailto:a@example.com>",
+			sent:     "Mail <x id=\"0\"><mailto:a@example.com></x>",
+			expected: "MAIL <mailto:a@example.com>",
+		},
+		{
+			name:     "with placeholders",
+			flags:    []string{"--markdown", "--protect-placeholders"},
+			text:     "Hi {name}, see `{code}`",
+			sent:     "Hi <x id=\"0\">{name}</x>, see <x id=\"1\">`{code}`</x>",
+			expected: "HI {name}, SEE `{code}`",
+		},
+		{
+			name:     "disabled",
+			text:     "See [the docs](/help) for `config`",
+			sent:     "See [the docs](/help) for `config`",
+			expected: "SEE [THE DOCS](/HELP) FOR `CONFIG`",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			testConfig(t, tc.flags...)
+
+			ut := &upperTranslator{}
+			translated, err := sendTranslationRequest(context.Background(), ut, translationRequest{
+				SourceLang:  "EN",
+				TargetLang:  "DE",
+				TagHandling: tagHandlingHTML,
+				Text:        tc.text,
+			})
+			if err != nil {
+				t.Fatalf("translating: %s", err)
+			}
+
+			if sent := ut.requests[0].Text; sent != tc.sent {
+				t.Errorf("sent %q, want %q", sent, tc.sent)
+			}
+			if translated != tc.expected {
+				t.Errorf("got %q, want %q", translated, tc.expected)
+			}
+		})
+	}
+}
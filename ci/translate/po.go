@@ -0,0 +1,327 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const poFlagFuzzy = "fuzzy"
+
+// poEntry represents a single message of a gettext PO file. The path of
+// the string is used as msgctxt to keep messages unique even if the
+// same reference string is used for multiple keys.
+type poEntry struct {
+	Comment string
+	Context string
+	ID      string
+	Str     string
+	Fuzzy   bool
+}
+
+var poStringEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	`"`, `\"`,
+	"\n", `\n`,
+	"\r", `\r`,
+	"\t", `\t`,
+)
+
+// exportPO writes one gettext PO file per target language into the
+// given directory. Translations not reviewed by a human are flagged as
+// fuzzy: removing the flag in the editor marks them reviewed on import.
+func exportPO(tf translationFile, dir string) error {
+	if !cfg.DryRun {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return errors.Wrap(err, "creating export directory")
+		}
+	}
+
+	for _, lang := range tf.Languages() {
+		entries := buildPOEntries(tf, lang)
+
+		filename := filepath.Join(dir, lang+".po")
+		if err := writeFileAtomic(filename, func(w io.Writer) error {
+			return writePO(w, lang, entries)
+		}); err != nil {
+			return errors.Wrapf(err, "writing language %s", lang)
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"lang":    lang,
+			"file":    filename,
+			"entries": len(entries),
+		}).Info("exported PO file")
+	}
+
+	return nil
+}
+
+func buildPOEntries(tf translationFile, lang string) (entries []poEntry) {
+	tm := tf.Translations[lang]
+
+	for _, key := range tf.ReferenceKeys() {
+		if tf.isNoTranslateKey(key) {
+			// Copied verbatim from the reference, nothing to review
+			continue
+		}
+
+		var (
+			sources = leafStrings(key, tf.Reference.Translations[key])
+			targets = leafStrings(key, tm.Translations[key])
+			paths   = make([]string, 0, len(sources))
+		)
+
+		for path := range sources {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+
+		for _, path := range paths {
+			target, ok := targets[path]
+			entries = append(entries, poEntry{
+				Comment: tf.Reference.Descriptions[key],
+				Context: path,
+				ID:      sources[path],
+				Str:     target,
+				Fuzzy:   ok && !tm.isReviewed(path),
+			})
+		}
+	}
+
+	return entries
+}
+
+func writePO(w io.Writer, lang string, entries []poEntry) error {
+	buf := new(strings.Builder)
+
+	buf.WriteString("msgid \"\"\nmsgstr \"\"\n")
+	for _, line := range []string{
+		"Language: " + lang,
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+		"Content-Transfer-Encoding: 8bit",
+	} {
+		fmt.Fprintf(buf, "\"%s\"\n", poStringEscaper.Replace(line+"\n"))
+	}
+
+	for _, e := range entries {
+		buf.WriteString("\n")
+		if e.Comment != "" {
+			for _, line := range strings.Split(e.Comment, "\n") {
+				fmt.Fprintf(buf, "#. %s\n", line)
+			}
+		}
+		if e.Fuzzy {
+			fmt.Fprintf(buf, "#, %s\n", poFlagFuzzy)
+		}
+		writePOString(buf, "msgctxt", e.Context)
+		writePOString(buf, "msgid", e.ID)
+		writePOString(buf, "msgstr", e.Str)
+	}
+
+	_, err := io.WriteString(w, buf.String())
+	return errors.Wrap(err, "writing PO")
+}
+
+// writePOString writes the keyword with the quoted value, multi-line
+// values are split after each newline as gettext tools do
+func writePOString(buf *strings.Builder, keyword, value string) {
+	if !strings.Contains(strings.TrimSuffix(value, "\n"), "\n") {
+		fmt.Fprintf(buf, "%s \"%s\"\n", keyword, poStringEscaper.Replace(value))
+		return
+	}
+
+	fmt.Fprintf(buf, "%s \"\"\n", keyword)
+	for _, line := range strings.SplitAfter(value, "\n") {
+		if line != "" {
+			fmt.Fprintf(buf, "\"%s\"\n", poStringEscaper.Replace(line))
+		}
+	}
+}
+
+// parsePO reads the messages from a PO file, the header is returned as
+// entry with empty msgid
+func parsePO(r io.Reader) ([]poEntry, error) {
+	var (
+		entries []poEntry
+		current poEntry
+		field   *string
+		hasStr  bool
+		lineNo  int
+	)
+
+	flush := func() {
+		if hasStr {
+			entries = append(entries, current)
+		}
+		current, field, hasStr = poEntry{}, nil, false
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+
+		if hasStr && strings.HasPrefix(line, "#") {
+			// Entries are not required to be separated by blank lines
+			flush()
+		}
+
+		switch {
+		case line == "":
+			flush()
+
+		case strings.HasPrefix(line, "#,"):
+			for _, flag := range strings.Split(line[2:], ",") {
+				if strings.TrimSpace(flag) == poFlagFuzzy {
+					current.Fuzzy = true
+				}
+			}
+
+		case strings.HasPrefix(line, "#"):
+			// Other comments are not imported
+
+		case strings.HasPrefix(line, "msgid_plural"), strings.HasPrefix(line, "msgstr["):
+			return nil, errors.Errorf("line %d: plural forms are not supported", lineNo)
+
+		case strings.HasPrefix(line, `"`):
+			if field == nil {
+				return nil, errors.Errorf("line %d: string without keyword", lineNo)
+			}
+			value, err := strconv.Unquote(line)
+			if err != nil {
+				return nil, errors.Wrapf(err, "line %d: unquoting string", lineNo)
+			}
+			*field += value
+
+		default:
+			keyword, quoted, _ := strings.Cut(line, " ")
+			value, err := strconv.Unquote(strings.TrimSpace(quoted))
+			if err != nil {
+				return nil, errors.Wrapf(err, "line %d: unquoting %s", lineNo, keyword)
+			}
+
+			if hasStr && (keyword == "msgctxt" || keyword == "msgid") {
+				flush()
+			}
+
+			switch keyword {
+			case "msgctxt":
+				field = &current.Context
+			case "msgid":
+				field = &current.ID
+			case "msgstr":
+				field, hasStr = &current.Str, true
+			default:
+				return nil, errors.Errorf("line %d: unknown keyword %q", lineNo, keyword)
+			}
+			*field = value
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "reading PO")
+	}
+
+	flush()
+	return entries, nil
+}
+
+// importPO reads all PO files from the given directory and puts their
+// translated, non-fuzzy messages into the translation file, marked as
+// reviewed
+func importPO(tf *translationFile, dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.po"))
+	if err != nil {
+		return errors.Wrap(err, "listing PO files")
+	}
+
+	for _, filename := range files {
+		if err = importPOFile(tf, filename); err != nil {
+			return errors.Wrapf(err, "importing %s", filename)
+		}
+	}
+
+	return nil
+}
+
+func importPOFile(tf *translationFile, filename string) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return errors.Wrap(err, "opening file")
+	}
+	defer f.Close()
+
+	entries, err := parsePO(f)
+	if err != nil {
+		return errors.Wrap(err, "parsing PO")
+	}
+
+	lang := strings.TrimSuffix(filepath.Base(filename), ".po")
+	for _, e := range entries {
+		if e.ID == "" && e.Context == "" {
+			lang = poHeaderLanguage(e.Str, lang)
+		}
+	}
+	lang = normalizeLanguageKey(lang)
+
+	tm := tf.Translations[lang]
+	if tm == nil {
+		logrus.WithFields(logrus.Fields{"file": filename, "lang": lang}).Warn("unknown target language, skipping")
+		return nil
+	}
+
+	tasks, verbatim, err := importTasks(tf, lang)
+	if err != nil {
+		return errors.Wrap(err, "collecting strings")
+	}
+
+	current := tm.leafStrings()
+
+	var imported int
+	for _, e := range entries {
+		if e.Context == "" || e.Fuzzy || e.Str == "" {
+			continue
+		}
+
+		task, ok := tasks[e.Context]
+		if !ok {
+			logrus.WithFields(logrus.Fields{"lang": lang, "msgctxt": e.Context}).Warn("message does not match any reference key, skipping")
+			continue
+		}
+
+		if verbatim[e.Context] || (current[e.Context] == e.Str && tm.isReviewed(e.Context)) {
+			continue
+		}
+
+		task.apply(e.Str)
+		tm.setSourceHash(task.Path, task.Source)
+		tm.setProvenance(task.Path, provenanceReviewed)
+		imported++
+	}
+
+	logrus.WithFields(logrus.Fields{"lang": lang, "entries": imported}).Info("imported PO file")
+	return nil
+}
+
+// poHeaderLanguage extracts the Language field from the PO header and
+// falls back to the given language if it is not set
+func poHeaderLanguage(header, fallback string) string {
+	for _, line := range strings.Split(header, "\n") {
+		if name, value, ok := strings.Cut(line, ":"); ok && strings.TrimSpace(name) == "Language" {
+			if value = strings.TrimSpace(value); value != "" {
+				return value
+			}
+		}
+	}
+	return fallback
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testRegionYAML = `reference:
+  languageKey: en
+  translations:
+    greeting: Hello
+translations:
+  pt-BR:
+    translations: {}
+`
+
+func TestImportPOLanguageIsNormalized(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		filename string
+		header   string
+	}{
+		{name: "header", filename: "messages.po", header: "pt_BR"},
+		{name: "lower-case header", filename: "messages.po", header: "pt-br"},
+		{name: "filename", filename: "pt_BR.po"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			testConfig(t)
+
+			tf := loadTestFile(t, testRegionYAML)
+
+			po := "msgid \"\"\nmsgstr \"\"\n"
+			if tc.header != "" {
+				po += "\"Language: " + tc.header + "\\n\"\n"
+			}
+			po += "\nmsgctxt \"greeting\"\nmsgid \"Hello\"\nmsgstr \"Olá\"\n"
+
+			filename := filepath.Join(t.TempDir(), tc.filename)
+			if err := os.WriteFile(filename, []byte(po), 0o600); err != nil {
+				t.Fatalf("writing PO: %s", err)
+			}
+
+			if err := importPOFile(&tf, filename); err != nil {
+				t.Fatalf("importing: %s", err)
+			}
+
+			if got := tf.Translations["pt-BR"].Translations["greeting"]; got != "Olá" {
+				t.Errorf("greeting = %v, want Olá", got)
+			}
+		})
+	}
+}
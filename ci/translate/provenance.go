@@ -0,0 +1,68 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	provenanceAuto     = "auto"
+	provenanceReviewed = "reviewed"
+)
+
+// provenance describes where the translation of a string came from:
+// "auto" for translations fetched from the translator, "reviewed" for
+// translations approved by a human (set manually in the YAML file).
+//
+// The entries are stored per string path in meta.provenance of the
+// language and serve as the list of machine-translated strings: tooling
+// looks for the paths in state "auto" instead of reading a separate
+// list. A second list next to the provenance would have to be kept in
+// sync on every write and could not hold the time of the translation.
+// Like the fingerprints, the entries never end up in the output.
+type provenance struct {
+	State   string    `yaml:"state"`
+	Updated time.Time `yaml:"updated"`
+}
+
+// isReviewed checks whether the string at the given path has been
+// approved by a human and therefore must not be overwritten
+func (t *translationMapping) isReviewed(path string) bool {
+	return t.Meta.Provenance[path].State == provenanceReviewed
+}
+
+func (t *translationMapping) setProvenance(path, state string) {
+	if t.Meta.Provenance == nil {
+		t.Meta.Provenance = make(map[string]provenance)
+	}
+	t.Meta.Provenance[path] = provenance{State: state, Updated: time.Now().UTC().Truncate(time.Second)}
+}
+
+// clearReview marks all strings present in the language as reviewed,
+// removing the markers of machine-translated strings
+func clearReview(tf *translationFile, lang string) error {
+	tm := tf.Translations[lang]
+	if tm == nil {
+		return errors.Errorf("unknown language %q (valid: %s)", lang, strings.Join(tf.Languages(), ", "))
+	}
+
+	var count int
+	for key, value := range tm.Translations {
+		for path := range leafStrings(key, value) {
+			if !tm.isReviewed(path) {
+				tm.setProvenance(path, provenanceReviewed)
+				count++
+			}
+		}
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"lang":  lang,
+		"count": count,
+	}).Info("marked strings as reviewed")
+
+	return nil
+}
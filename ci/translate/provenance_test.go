@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testProvenanceYAML = `reference:
+  deeplLanguage: EN
+  translations:
+    auto: Changed
+    reviewed: Changed
+    current: Current
+translations:
+  de:
+    deeplLanguage: DE
+    translations:
+      auto: Alt
+      reviewed: Geprüft
+      current: Aktuell
+    meta:
+      sourceHashes:
+        auto: 00000000
+        reviewed: 00000000
+      provenance:
+        auto:
+          state: auto
+          updated: 2024-01-01T00:00:00Z
+        reviewed:
+          state: reviewed
+          updated: 2024-01-01T00:00:00Z
+`
+
+func TestReviewedTranslationsAreKept(t *testing.T) {
+	testConfig(t, "--deepl-api-key=test", "--concurrency=1")
+
+	ft := &fakeTranslator{}
+	tf := loadTestFile(t, testProvenanceYAML)
+
+	if err := autoTranslate(context.Background(), ft, &tf); err != nil {
+		t.Fatalf("translating: %s", err)
+	}
+
+	if calls := ft.texts(); !reflect.DeepEqual(calls, []string{"EN>DE Changed"}) {
+		t.Errorf("unexpected calls: %q", calls)
+	}
+
+	de := tf.Translations["de"]
+	for key, expected := range map[string]string{
+		"auto":     "DE:Changed",
+		"reviewed": "Geprüft",
+		"current":  "Aktuell",
+	} {
+		if got := de.Translations[key]; got != expected {
+			t.Errorf("%s = %q, want %q", key, got, expected)
+		}
+	}
+
+	if stale := summary.Report().Languages["de"].Stale; stale != 1 {
+		t.Errorf("expected 1 stale translation, got %d", stale)
+	}
+
+	if !de.isReviewed("reviewed") || de.isReviewed("auto") {
+		t.Errorf("unexpected provenance: %#v", de.Meta.Provenance)
+	}
+}
+
+func TestProvenanceRoundTrip(t *testing.T) {
+	testConfig(t)
+
+	tf := loadTestFile(t, testProvenanceYAML)
+	if err := saveTranslationFile(tf); err != nil {
+		t.Fatalf("saving: %s", err)
+	}
+
+	reloaded, err := loadTranslationFile()
+	if err != nil {
+		t.Fatalf("loading: %s", err)
+	}
+
+	if !reflect.DeepEqual(tf.Translations["de"].Meta, reloaded.Translations["de"].Meta) {
+		t.Errorf("metadata changed on round-trip:\n got: %#v\nwant: %#v", reloaded.Translations["de"].Meta, tf.Translations["de"].Meta)
+	}
+}
+
+func TestClearReview(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		lang     string
+		fail     bool
+		reviewed []string
+	}{
+		{name: "known language", lang: "de", reviewed: []string{"auto", "current", "reviewed"}},
+		{name: "unknown language", lang: "fr", fail: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			testConfig(t)
+
+			tf := loadTestFile(t, testProvenanceYAML)
+			if err := clearReview(&tf, tc.lang); (err != nil) != tc.fail {
+				t.Fatalf("clearReview() = %v, want failure %v", err, tc.fail)
+			}
+
+			de := tf.Translations["de"]
+			for _, path := range tc.reviewed {
+				if !de.isReviewed(path) {
+					t.Errorf("%s not marked reviewed", path)
+				}
+			}
+			if tc.fail && de.isReviewed("auto") {
+				t.Error("failed clearReview modified the translations")
+			}
+		})
+	}
+}
+
+func TestProvenanceNotRendered(t *testing.T) {
+	for _, format := range []string{"go", "js", "json", "ts"} {
+		t.Run(format, func(t *testing.T) {
+			testConfig(t, "--output-format="+format, "--force-render")
+
+			tf := loadTestFile(t, testProvenanceYAML)
+			tf.Translations[tf.Reference.LanguageKey] = &tf.Reference
+
+			if err := outputRenderers[format](tf); err != nil {
+				t.Fatalf("rendering: %s", err)
+			}
+
+			out := readTestFile(t, cfg.OutputFile)
+			for _, marker := range []string{"provenance", "sourceHashes", "00000000", "2024-01-01"} {
+				if strings.Contains(out, marker) {
+					t.Errorf("output contains %q:\n%s", marker, out)
+				}
+			}
+		})
+	}
+}
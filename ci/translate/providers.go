@@ -0,0 +1,59 @@
+package main
+
+import "context"
+
+// providerSet holds the translators used for a run, keyed by their
+// type, together with their quota guards. Both are created on first use
+// so unused providers are never configured or queried.
+type providerSet struct {
+	quotas      map[string]*quotaGuard
+	translators map[string]translator
+}
+
+func newProviderSet(defaultTranslator translator) *providerSet {
+	return &providerSet{
+		quotas:      make(map[string]*quotaGuard),
+		translators: map[string]translator{cfg.Translator: defaultTranslator},
+	}
+}
+
+// forLanguage returns the type and the translator configured for the
+// given language
+func (p *providerSet) forLanguage(tm *translationMapping) (string, translator, error) {
+	name := tm.provider()
+	if t, ok := p.translators[name]; ok {
+		return name, t, nil
+	}
+
+	t, err := getTranslatorByType(name)
+	if err != nil {
+		return name, nil, err
+	}
+
+	p.translators[name] = t
+	return name, t, nil
+}
+
+// quota returns the quota guard of the given provider, the guard is
+// shared by all languages using the provider
+func (p *providerSet) quota(ctx context.Context, name string) (*quotaGuard, error) {
+	if q, ok := p.quotas[name]; ok {
+		return q, nil
+	}
+
+	q, err := newQuotaGuard(ctx, p.translators[name], cfg.QuotaSafetyMargin)
+	if err != nil {
+		return nil, err
+	}
+
+	p.quotas[name] = q
+	return q, nil
+}
+
+// provider returns the translator type to use for the language
+func (t *translationMapping) provider() string {
+	if t.Provider != "" {
+		return t.Provider
+	}
+	return cfg.Translator
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+var errQuotaExceeded = errors.New("translation quota would be exceeded")
+
+// quotaGuard keeps track of the characters used at the translator and
+// prevents translating more than the account allows
+type quotaGuard struct {
+	used, limit, margin int64
+}
+
+// newQuotaGuard queries the current usage from the translator, in case
+// the translator does not report usage a nil guard is returned which
+// does not limit translations
+func newQuotaGuard(ctx context.Context, t translator, margin int64) (*quotaGuard, error) {
+	ur, ok := t.(usageReporter)
+	if !ok {
+		return nil, nil
+	}
+
+	used, limit, err := ur.Usage(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetching usage")
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"used":  used,
+		"limit": limit,
+	}).Debug("translator character usage")
+
+	return &quotaGuard{used: used, limit: limit, margin: margin}, nil
+}
+
+// Reserve checks whether the given amount of characters can be
+// translated and accounts them as used
+func (q *quotaGuard) Reserve(chars int64) error {
+	if q == nil || q.limit == 0 {
+		return nil
+	}
+
+	if q.used+chars > q.limit-q.margin {
+		return errors.Wrapf(errQuotaExceeded, "%d characters requested, %d of %d used, margin %d", chars, q.used, q.limit, q.margin)
+	}
+
+	q.used += chars
+	return nil
+}
+
+// fetchUsage queries the character usage of the translator account
+func fetchUsage(ctx context.Context, t translator) (used, limit int64, err error) {
+	ur, ok := t.(usageReporter)
+	if !ok {
+		return 0, 0, errors.Errorf("translator %q does not report usage", cfg.Translator)
+	}
+
+	return ur.Usage(ctx)
+}
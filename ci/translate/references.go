@@ -0,0 +1,164 @@
+package main
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// sourceFor returns the language and mapping to take the source string
+// of the key from when translating into the given language: the
+// reference if it contains the key, otherwise the first fallback
+// reference containing it. The target language itself is never used as
+// its own source.
+func (t translationFile) sourceFor(key, lang string) (string, *translationMapping) {
+	if _, ok := t.Reference.Translations[key]; ok {
+		return t.Reference.LanguageKey, &t.Reference
+	}
+
+	for _, ref := range t.FallbackReferences {
+		tm := t.Translations[ref]
+		if ref == lang || tm == nil {
+			continue
+		}
+
+		if _, ok := tm.Translations[key]; ok {
+			return ref, tm
+		}
+	}
+
+	return "", nil
+}
+
+// isSourceKey reports whether the key is present in the reference or
+// in any of the fallback references
+func (t translationFile) isSourceKey(key string) bool {
+	if _, ok := t.Reference.Translations[key]; ok {
+		return true
+	}
+
+	for _, ref := range t.FallbackReferences {
+		if tm := t.Translations[ref]; tm != nil {
+			if _, ok := tm.Translations[key]; ok {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// sourceKeys returns the keys of the reference and the fallback
+// references in sorted order
+func (t translationFile) sourceKeys() []string {
+	keys := t.ReferenceKeys()
+
+	for _, ref := range t.FallbackReferences {
+		tm := t.Translations[ref]
+		if tm == nil {
+			continue
+		}
+
+		for key := range tm.Translations {
+			if _, ok := t.Reference.Translations[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+	}
+
+	sort.Strings(keys)
+	return uniqueStrings(keys)
+}
+
+// uniqueStrings removes consecutive duplicates from the sorted list
+func uniqueStrings(sorted []string) []string {
+	out := sorted[:0]
+	for i, s := range sorted {
+		if i == 0 || s != sorted[i-1] {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// referenceOverride records the original reference and the mapping of
+// the language used as reference in its place
+type referenceOverride struct {
+	lang      string
+	mapping   *translationMapping
+	reference translationMapping
+}
+
+// overrideReference makes the given language of the translations the
+// reference for the run: it is removed from the translations and the
+// reference is set aside (not being translated into) until restored by
+// withOriginalReference. The language must contain all strings of the
+// reference, descriptions and tag-handling of the reference are kept.
+func (t *translationFile) overrideReference(lang string) error {
+	tm := t.Translations[lang]
+	switch {
+	case lang == t.Reference.LanguageKey:
+		return nil
+	case tm == nil:
+		return errors.Errorf("unknown language %q (valid: %s)", lang, strings.Join(t.Languages(), ", "))
+	}
+
+	var missing []string
+	for _, key := range t.ReferenceKeys() {
+		have := leafStrings(key, tm.Translations[key])
+		for path := range leafStrings(key, t.Reference.Translations[key]) {
+			if _, ok := have[path]; !ok {
+				missing = append(missing, path)
+			}
+		}
+	}
+
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return errors.Errorf("language %q is missing %d strings of the reference: %s", lang, len(missing), strings.Join(missing, ", "))
+	}
+
+	ref := *tm
+	if ref.LanguageKey == "" {
+		ref.LanguageKey = lang
+	}
+	ref.Descriptions, ref.TagHandling = t.Reference.Descriptions, t.Reference.TagHandling
+
+	translations := make(map[string]*translationMapping, len(t.Translations))
+	for l, m := range t.Translations {
+		if l != lang {
+			translations[l] = m
+		}
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"lang":      lang,
+		"reference": t.Reference.LanguageKey,
+	}).Info("using language as reference")
+
+	t.override = &referenceOverride{lang: lang, mapping: tm, reference: t.Reference}
+	t.Reference, t.Translations = ref, translations
+
+	return nil
+}
+
+// withOriginalReference returns the translation file with its original
+// reference in place if the reference has been overridden
+func (t translationFile) withOriginalReference() translationFile {
+	if t.override == nil {
+		return t
+	}
+
+	out := t
+	out.Translations = make(map[string]*translationMapping, len(t.Translations)+1)
+	for lang, tm := range t.Translations {
+		out.Translations[lang] = tm
+	}
+	out.Translations[t.override.lang] = t.override.mapping
+	out.Reference = t.override.reference
+	out.override = nil
+
+	return out
+}
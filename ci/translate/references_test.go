@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestOverrideReferenceLeavesTranslationsAlone(t *testing.T) {
+	testConfig(t, "--deepl-api-key=test", "--concurrency=1")
+
+	ft := &fakeTranslator{}
+	tf := loadTestFile(t, `reference:
+  deeplLanguage: EN
+  languageKey: en
+  translations:
+    added: New
+    farewell: Bye
+    greeting: Hello
+translations:
+  de:
+    deeplLanguage: DE
+    translations:
+      added: Neu
+      farewell: Tschüss
+      greeting: Hallo
+  es:
+    deeplLanguage: ES
+    translations:
+      added: Nuevo
+      farewell: Adiós
+      greeting: Hola
+  fr:
+    deeplLanguage: FR
+    translations:
+      greeting: Bonjour
+    meta:
+      sourceHashes:
+        greeting: `+sourceHash("Hello")+`
+`)
+	fingerprints := map[string]map[string]string{}
+	for lang, tm := range tf.Translations {
+		fingerprints[lang] = copyStringMap(tm.Meta.SourceHashes)
+	}
+
+	if err := tf.overrideReference("de"); err != nil {
+		t.Fatalf("overriding reference: %s", err)
+	}
+	if err := autoTranslate(context.Background(), ft, &tf); err != nil {
+		t.Fatalf("translating with overridden reference: %s", err)
+	}
+	tf = tf.withOriginalReference()
+
+	if calls := ft.texts(); !reflect.DeepEqual(calls, []string{"DE>FR Neu", "DE>FR Tschüss"}) {
+		t.Errorf("unexpected calls: %q", calls)
+	}
+
+	expected := translation{"added": "FR:Neu", "farewell": "FR:Tschüss", "greeting": "Bonjour"}
+	if got := tf.Translations["fr"].Translations; !reflect.DeepEqual(got, expected) {
+		t.Errorf("unexpected fr translations: %#v", got)
+	}
+	if got := tf.Translations["es"].Translations["greeting"]; got != "Hola" {
+		t.Errorf("es greeting changed to %q", got)
+	}
+
+	for lang, tm := range tf.Translations {
+		if got := copyStringMap(tm.Meta.SourceHashes); !reflect.DeepEqual(got, fingerprints[lang]) {
+			t.Errorf("%s fingerprints changed by override run:\n got: %v\nwant: %v", lang, got, fingerprints[lang])
+		}
+	}
+
+	// The next run with the original reference keeps everything
+	if err := saveTranslationFile(tf); err != nil {
+		t.Fatalf("saving: %s", err)
+	}
+	tf, err := loadTranslationFile()
+	if err != nil {
+		t.Fatalf("loading: %s", err)
+	}
+
+	ft.requests = nil
+	if err = autoTranslate(context.Background(), ft, &tf); err != nil {
+		t.Fatalf("translating: %s", err)
+	}
+	if calls := ft.texts(); len(calls) > 0 {
+		t.Errorf("normal run after override run re-translated: %q", calls)
+	}
+	if got := tf.Translations["fr"].Translations; !reflect.DeepEqual(got, expected) {
+		t.Errorf("unexpected fr translations after normal run: %#v", got)
+	}
+}
+
+func copyStringMap(m map[string]string) map[string]string {
+	out := make(map[string]string, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"math"
+	"unicode/utf8"
+
+	"github.com/sirupsen/logrus"
+)
+
+type (
+	// catalogStats summarizes the translation file without contacting
+	// any translator
+	catalogStats struct {
+		Keys      int                      `json:"keys"`
+		KeyTypes  keyTypeStats             `json:"keyTypes"`
+		Languages map[string]languageStats `json:"languages"`
+	}
+
+	// keyTypeStats counts the reference keys by the type of their value
+	keyTypeStats struct {
+		Lists   int `json:"lists"`
+		Nested  int `json:"nested"`
+		Other   int `json:"other"`
+		Strings int `json:"strings"`
+	}
+
+	languageStats struct {
+		Characters int64   `json:"characters"`
+		Percentage float64 `json:"percentage"`
+		Reference  bool    `json:"reference,omitempty"`
+		Strings    int     `json:"strings"`
+		Translated int     `json:"translated"`
+	}
+)
+
+// calculateStats counts the reference keys by type and the translated
+// keys, strings and characters of the reference and every language
+func calculateStats(tf translationFile) catalogStats {
+	stats := catalogStats{
+		Keys:      len(tf.Reference.Translations),
+		Languages: make(map[string]languageStats),
+	}
+
+	for _, value := range tf.Reference.Translations {
+		switch normalizeValue(value).(type) {
+		case string:
+			stats.KeyTypes.Strings++
+		case []any:
+			stats.KeyTypes.Lists++
+		case map[string]any:
+			stats.KeyTypes.Nested++
+		default:
+			stats.KeyTypes.Other++
+		}
+	}
+
+	ref := mappingStats(tf.Reference.Translations)
+	ref.Translated, ref.Percentage, ref.Reference = stats.Keys, 100, true
+	stats.Languages[tf.Reference.LanguageKey] = ref
+
+	for _, lc := range calculateCompleteness(tf) {
+		ls := mappingStats(tf.Translations[lc.Lang].Translations)
+		ls.Translated, ls.Percentage = lc.Translated, math.Round(lc.Percentage()*10)/10
+		stats.Languages[lc.Lang] = ls
+	}
+
+	return stats
+}
+
+// mappingStats counts the strings and their characters in the given
+// translations
+func mappingStats(t translation) (ls languageStats) {
+	for key, value := range t {
+		for _, str := range leafStrings(key, value) {
+			ls.Strings++
+			ls.Characters += int64(utf8.RuneCountInString(str))
+		}
+	}
+	return ls
+}
+
+// logStats prints the key types and one line per language
+func logStats(tf translationFile, stats catalogStats) {
+	logrus.WithFields(logrus.Fields{
+		"keys":    stats.Keys,
+		"strings": stats.KeyTypes.Strings,
+		"lists":   stats.KeyTypes.Lists,
+		"nested":  stats.KeyTypes.Nested,
+		"other":   stats.KeyTypes.Other,
+	}).Info("reference keys")
+
+	langs := append([]string{tf.Reference.LanguageKey}, tf.Languages()...)
+	for _, lang := range langs {
+		ls := stats.Languages[lang]
+		logrus.WithFields(logrus.Fields{
+			"lang":       lang,
+			"reference":  ls.Reference,
+			"translated": ls.Translated,
+			"percent":    ls.Percentage,
+			"strings":    ls.Strings,
+			"characters": ls.Characters,
+		}).Info("language statistics")
+	}
+}
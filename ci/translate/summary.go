@@ -0,0 +1,135 @@
+package main
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+type (
+	// runSummary collects counters about the strings processed during
+	// the run for the summary logged at the end
+	runSummary struct {
+		langs map[string]*languageSummary
+		mu    sync.Mutex
+	}
+
+	languageSummary struct {
+		// APICalls and Characters count the requests sent to the
+		// translator and the source characters contained in them
+		APICalls   int   `json:"apiCalls"`
+		Characters int64 `json:"characters"`
+		Copied     int   `json:"copied"`
+		// Deduplicated counts translations reused from an identical
+		// request of the same run instead of calling the translator
+		Deduplicated int `json:"deduplicated"`
+		Failed       int `json:"failed"`
+		// Stale counts reviewed translations kept although the
+		// reference changed since they were reviewed
+		Stale      int `json:"stale"`
+		Translated int `json:"translated"`
+		Unchanged  int `json:"unchanged"`
+	}
+
+	// metricsReport is written to the metrics-file
+	metricsReport struct {
+		Languages map[string]languageSummary `json:"languages"`
+		Totals    metricsTotals              `json:"totals"`
+	}
+
+	metricsTotals struct {
+		languageSummary
+		// Skipped counts the strings not sent to the translator as they
+		// were up-to-date or deduplicated
+		Skipped int `json:"skipped"`
+	}
+)
+
+var summary = &runSummary{langs: make(map[string]*languageSummary)}
+
+func (r *runSummary) update(lang string, fn func(*languageSummary)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.langs[lang] == nil {
+		r.langs[lang] = &languageSummary{}
+	}
+	fn(r.langs[lang])
+}
+
+// Reset clears all counters
+func (r *runSummary) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.langs = make(map[string]*languageSummary)
+}
+
+// Report returns a copy of the counters per language and their totals
+func (r *runSummary) Report() metricsReport {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	report := metricsReport{Languages: make(map[string]languageSummary, len(r.langs))}
+	for lang, ls := range r.langs {
+		report.Languages[lang] = *ls
+
+		t := &report.Totals
+		t.APICalls += ls.APICalls
+		t.Characters += ls.Characters
+		t.Copied += ls.Copied
+		t.Deduplicated += ls.Deduplicated
+		t.Failed += ls.Failed
+		t.Stale += ls.Stale
+		t.Translated += ls.Translated
+		t.Unchanged += ls.Unchanged
+		t.Skipped += ls.Unchanged + ls.Deduplicated
+	}
+
+	return report
+}
+
+// Log prints one line per language and a line containing the totals
+func (r *runSummary) Log() {
+	report := r.Report()
+
+	langs := make([]string, 0, len(report.Languages))
+	for lang := range report.Languages {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+
+	for _, lang := range langs {
+		ls := report.Languages[lang]
+		logrus.WithFields(logrus.Fields{
+			"lang":         lang,
+			"translated":   ls.Translated,
+			"unchanged":    ls.Unchanged,
+			"copied":       ls.Copied,
+			"deduplicated": ls.Deduplicated,
+			"failed":       ls.Failed,
+			"stale":        ls.Stale,
+			"api_calls":    ls.APICalls,
+			"characters":   ls.Characters,
+		}).Info("translation summary")
+	}
+
+	total := report.Totals
+	logrus.WithFields(logrus.Fields{
+		"translated":   total.Translated,
+		"unchanged":    total.Unchanged,
+		"copied":       total.Copied,
+		"deduplicated": total.Deduplicated,
+		"failed":       total.Failed,
+		"stale":        total.Stale,
+		"skipped":      total.Skipped,
+		"api_calls":    total.APICalls,
+		"characters":   total.Characters,
+	}).Info("translation summary (all languages)")
+}
+
+// WriteMetrics writes the counters as JSON document into the given file
+func (r *runSummary) WriteMetrics(filename string) error {
+	return writeJSONFile(filename, r.Report())
+}
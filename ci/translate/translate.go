@@ -2,31 +2,41 @@ package main
 
 import (
 	"context"
-	"encoding/json"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"os"
+	"os/signal"
+	"path"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
-	"text/template"
+	"sync"
+	"syscall"
 	"time"
+	"unicode/utf8"
 
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
 	"gopkg.in/yaml.v3"
 
 	"github.com/Luzifer/rconfig/v2"
 )
 
 const (
-	deeplRequestTimeout = 10 * time.Second
-	jsTemplate          = `// Auto-Generated, do not edit!
-
-export default {
-{{- range $lang, $translation := .Translations }}
-  '{{ $lang }}': JSON.parse('{{ .Translations.ToJSON }}'),
-{{- end }}
-}
-`
+	// defaultOutputFile is the default of the output-file flag, its
+	// extension is replaced to match the output-format
+	defaultOutputFile = "../../src/langs/langs.js"
+	sourceHashLength  = 8
+	// stdioFilename given as translation-file or output-file reads from
+	// stdin or writes to stdout
+	stdioFilename = "-"
 )
 
 type (
@@ -34,29 +44,196 @@ type (
 	translationFile struct {
 		Reference    translationMapping             `yaml:"reference"`
 		Translations map[string]*translationMapping `yaml:"translations"`
+		// NoTranslate contains glob patterns of keys to copy verbatim
+		// from the reference instead of translating them
+		NoTranslate []string `yaml:"noTranslate,omitempty"`
+		// DoNotTranslate is an alias for NoTranslate
+		DoNotTranslate []string `yaml:"doNotTranslate,omitempty"`
+		// FallbackReferences contains languages to take the source
+		// string from, in order, if a key is missing in the reference
+		FallbackReferences []string `yaml:"fallbackReferences,omitempty"`
+
+		// source contains the node tree loaded from disk to preserve
+		// its comments when saving
+		source *yaml.Node
+		// override is set while a language of the translations acts
+		// as reference for the run
+		override *referenceOverride
 	}
 	translationMapping struct {
 		DeeplLanguage string      `yaml:"deeplLanguage,omitempty"`
 		LanguageKey   string      `yaml:"languageKey,omitempty"`
 		Translations  translation `yaml:"translations"`
+		GlossaryID    string      `yaml:"glossaryId,omitempty"`
+		// BCP47 and Dir describe the locale in the rendered output, they
+		// default to the language key and the direction of the language
+		BCP47 string `yaml:"bcp47,omitempty"`
+		Dir   string `yaml:"dir,omitempty"`
+		// Provider selects the translator for this language, the
+		// translator flag is used when unset
+		Provider string `yaml:"provider,omitempty"`
+		// Descriptions contains optional notes about the usage of the
+		// keys to improve the translation quality (reference only). They
+		// are passed as context to the translator and never stored in the
+		// translations, DeepL does not bill the context characters.
+		Descriptions map[string]string `yaml:"descriptions,omitempty"`
+		// TagHandling overrides the deepl-tag-handling flag for single keys
+		// (reference only)
+		TagHandling map[string]string `yaml:"tagHandling,omitempty"`
+		Meta        translationMeta   `yaml:"meta,omitempty"`
+	}
+	// taskFilter decides whether a task is created for the string at
+	// the given path
+	taskFilter func(path, src string, exists bool) bool
+	// translationTask represents a single reference string to be
+	// translated into the given language
+	translationTask struct {
+		Lang   string
+		Path   string
+		Source string
+
+		apply      func(value string)
+		translator translator
+
+		// sourceLang and sourceMapping reference the language the source
+		// string was taken from (the reference or a fallback reference)
+		sourceLang    string
+		sourceMapping *translationMapping
+	}
+	translationMeta struct {
+		// SourceHashes contains the fingerprint of the reference string
+		// the translation was created from, keyed by the path of the
+		// string (see leafStrings)
+		SourceHashes map[string]string `yaml:"sourceHashes,omitempty"`
+		// Provenance records whether the string at the given path has
+		// been translated automatically or reviewed by a human
+		Provenance map[string]provenance `yaml:"provenance,omitempty"`
 	}
 )
 
 var (
 	cfg = struct {
-		DeeplAPIEndpoint string `flag:"deepl-api-endpoint" default:"https://api-free.deepl.com/v2/translate" description:"DeepL API endpoint to request translations from"`
-		DeeplAPIKey      string `flag:"deepl-api-key" default:"" description:"API key for the DeepL API"`
-		OutputFile       string `flag:"output-file,o" default:"../../src/langs/langs.js" description:"Where to put rendered translations"`
-		TranslationFile  string `flag:"translation-file,t" default:"../../i18n.yaml" description:"File to use for translations"`
-		LogLevel         string `flag:"log-level" default:"info" description:"Log level (debug, info, warn, error, fatal)"`
-		VersionAndExit   bool   `flag:"version" default:"false" description:"Prints current version and exits"`
+		AddLanguage             string        `flag:"add-language" vardefault:"add-language" default:"" description:"Add an empty target language with this language key to the translation file and exit"`
+		AddMissing              bool          `flag:"add-missing" vardefault:"add-missing" default:"false" description:"Add the keys found by extract but missing in the reference with empty strings"`
+		Backup                  bool          `flag:"backup" vardefault:"backup" default:"false" description:"Copy the translation file to <translation-file>.bak before overwriting it"`
+		Check                   bool          `flag:"check" vardefault:"check" default:"false" description:"Check for missing translations and exit non-zero if any are found (no translation, no files written)"`
+		ClearReview             string        `flag:"clear-review" vardefault:"clear-review" default:"" description:"Mark all strings of this language as reviewed (clearing the machine-translated markers) and exit"`
+		CompletenessThreshold   float64       `flag:"completeness-threshold" vardefault:"completeness-threshold" default:"0" description:"Fail if any language is less complete than this percentage (0-100)"`
+		Concurrency             int           `flag:"concurrency" vardefault:"concurrency" default:"4" description:"Number of translations to fetch in parallel"`
+		Config                  string        `flag:"config" default:"" description:"YAML or JSON file to load settings from, keys are the flag names (precedence: flags > env as upper-case flag name > config file > defaults)"`
+		ContinueOnError         bool          `flag:"continue-on-error" vardefault:"continue-on-error" default:"false" description:"Keep translating when a translation fails, save the result and report all failures at the end"`
+		DecodeEntities          bool          `flag:"decode-entities" vardefault:"decode-entities" default:"true" description:"Decode HTML entities DeepL added to translations with html tag-handling (entities of the source are kept)"`
+		DeeplAPIEndpoint        string        `flag:"deepl-api-endpoint" vardefault:"deepl-api-endpoint" default:"" description:"DeepL API endpoint to request translations from (default: detected from API key)"`
+		DeeplAPIKey             string        `flag:"deepl-api-key" vardefault:"deepl-api-key" default:"" description:"API key for the DeepL API"`
+		DeeplMaxRetries         int           `flag:"deepl-max-retries" vardefault:"deepl-max-retries" default:"3" description:"Number of times a DeepL request failing with HTTP 429 or 5xx is retried with exponential backoff (0 = no retries)"`
+		DeeplPreserveFormatting bool          `flag:"deepl-preserve-formatting" vardefault:"deepl-preserve-formatting" default:"false" description:"Keep DeepL from correcting formatting like punctuation and capitalization"`
+		DeeplPricePerMillion    float64       `flag:"deepl-price-per-million" vardefault:"deepl-price-per-million" default:"0" description:"Price per million characters to estimate the cost of DeepL translations before translating"`
+		DeeplRequestTimeout     time.Duration `flag:"deepl-request-timeout" vardefault:"deepl-request-timeout" default:"10s" description:"Timeout of a single request to the DeepL API"`
+		DeeplSplitSentences     string        `flag:"deepl-split-sentences" vardefault:"deepl-split-sentences" default:"" description:"DeepL sentence splitting: 0, 1 or nonewlines (default: DeepL behavior)"`
+		DeeplTagHandling        string        `flag:"deepl-tag-handling" vardefault:"deepl-tag-handling" default:"" description:"How to treat markup in the strings: html, xml or off (plain text) (default: html)"`
+		Diff                    bool          `flag:"diff" vardefault:"diff" default:"false" description:"Print the strings added, changed and removed per language to stdout (combine with dry-run to preview)"`
+		DiffReport              string        `flag:"diff-report" vardefault:"diff-report" default:"" description:"Write the strings added, changed and removed per language and their counts as JSON into this file"`
+		DocumentThreshold       int           `flag:"document-threshold" vardefault:"document-threshold" default:"0" description:"Translate strings larger than this many bytes through the DeepL document API (0 = disabled, DeepL bills at least 50000 characters per document)"`
+		DryRun                  bool          `flag:"dry-run" vardefault:"dry-run" default:"false" description:"Report strings to translate and files to write without doing so"`
+		DynamicKeys             []string      `flag:"dynamic-keys" vardefault:"dynamic-keys" default:"" description:"Glob patterns of keys referenced dynamically in the source files, never reported as unused"`
+		ExportCSV               string        `flag:"export-csv" vardefault:"export-csv" default:"" description:"Export reference strings and translations into this CSV file and exit"`
+		ExportName              string        `flag:"export-name" vardefault:"export-name" default:"" description:"Name of the export containing the translations instead of the default export (js, ts output-format only)"`
+		ExportPO                string        `flag:"export-po" vardefault:"export-po" default:"" description:"Export reference strings and translations as gettext PO files into this directory and exit"`
+		ExportXLIFF             string        `flag:"export-xliff" vardefault:"export-xliff" default:"" description:"Export reference strings and translations as XLIFF 1.2 files into this directory and exit"`
+		Extract                 string        `flag:"extract" vardefault:"extract" default:"" description:"Search the files in this directory for translation calls, report keys missing in the reference and unused keys and exit"`
+		Fallback                bool          `flag:"fallback" vardefault:"fallback" default:"false" description:"Fill missing strings in the rendered output from the fallback-language and the reference (translation file is not changed)"`
+		FallbackLanguage        string        `flag:"fallback-language" vardefault:"fallback-language" default:"" description:"Language to take missing strings from before falling back to the reference (fallback only)"`
+		FindUnused              string        `flag:"find-unused" vardefault:"find-unused" default:"" description:"Search the files in this directory for the reference keys and exit non-zero if any is unused (no files written)"`
+		Flatten                 bool          `flag:"flatten" vardefault:"flatten" default:"false" description:"Collapse nested keys into dotted keys (lists into indexed keys) in the rendered output (translation file is not changed)"`
+		Force                   bool          `flag:"force" vardefault:"force" default:"false" description:"Re-translate all strings, even if they are up-to-date (reviewed translations are kept)"`
+		ForceRender             bool          `flag:"force-render" vardefault:"force-render" default:"false" description:"Render the output even if it is up to date (js, ts, go output-format only)"`
+		ForceRetranslate        []string      `flag:"force-retranslate" vardefault:"force-retranslate" default:"" description:"Glob patterns of keys to re-translate, even if they are up-to-date"`
+		GoPackage               string        `flag:"go-package" vardefault:"go-package" default:"langs" description:"Package name of the rendered file (go output-format only)"`
+		HTTPIdleConnsPerHost    int           `flag:"http-idle-conns-per-host" vardefault:"http-idle-conns-per-host" default:"0" description:"Number of idle connections to keep per API host (0 = concurrency)"`
+		HTTPIdleTimeout         time.Duration `flag:"http-idle-timeout" vardefault:"http-idle-timeout" default:"90s" description:"Time after which idle connections are closed"`
+		HTTPMaxIdleConns        int           `flag:"http-max-idle-conns" vardefault:"http-max-idle-conns" default:"100" description:"Number of idle connections to keep across all API hosts (0 = unlimited)"`
+		ImportCSV               string        `flag:"import-csv" vardefault:"import-csv" default:"" description:"Import changed translations from this CSV file as reviewed translations and exit"`
+		ImportPO                string        `flag:"import-po" vardefault:"import-po" default:"" description:"Import translated, non-fuzzy messages from the PO files in this directory as reviewed translations and exit"`
+		ImportXLIFF             string        `flag:"import-xliff" vardefault:"import-xliff" default:"" description:"Import translated units from the XLIFF files in this directory as reviewed translations and exit"`
+		Init                    string        `flag:"init" vardefault:"init" default:"" description:"Create the translation-file from the keys in this file (one per line, - for stdin) with the given languages and exit"`
+		LLMAPIKey               string        `flag:"llm-api-key" vardefault:"llm-api-key" default:"" description:"API key for the OpenAI compatible API"`
+		LLMEndpoint             string        `flag:"llm-endpoint" vardefault:"llm-endpoint" default:"https://api.openai.com/v1/chat/completions" description:"OpenAI compatible chat completions endpoint to request translations from"`
+		LLMMaxRetries           int           `flag:"llm-max-retries" vardefault:"llm-max-retries" default:"3" description:"Number of times an LLM request failing with HTTP 429 or 5xx is retried with exponential backoff (0 = no retries)"`
+		LLMModel                string        `flag:"llm-model" vardefault:"llm-model" default:"" description:"Model to use for LLM translations"`
+		LLMRequestTimeout       time.Duration `flag:"llm-request-timeout" vardefault:"llm-request-timeout" default:"60s" description:"Timeout of a single request to the LLM API"`
+		LangKeyStyle            string        `flag:"lang-key-style" vardefault:"lang-key-style" default:"bcp47" description:"Normalize language keys to bcp47 (en-US) or underscore (en_US) style, or keep them as they are"`
+		Languages               []string      `flag:"languages" vardefault:"languages" default:"" description:"Only translate these languages (comma-separated language keys), all others are left untouched"`
+		LibreAPIKey             string        `flag:"libre-api-key" vardefault:"libre-api-key" default:"" description:"API key for the LibreTranslate API (if required by the instance)"`
+		LibreEndpoint           string        `flag:"libre-endpoint" vardefault:"libre-endpoint" default:"" description:"LibreTranslate API endpoint to request translations from"`
+		LibreRequestTimeout     time.Duration `flag:"libre-request-timeout" vardefault:"libre-request-timeout" default:"10s" description:"Timeout of a single request to the LibreTranslate API"`
+		LogFormat               string        `flag:"log-format" vardefault:"log-format" default:"text" description:"Log format (text, json)"`
+		LogLevel                string        `flag:"log-level" vardefault:"log-level" default:"info" description:"Log level (debug, info, warn, error, fatal)"`
+		Markdown                bool          `flag:"markdown" vardefault:"markdown" default:"false" description:"Keep code, link targets and URLs in Markdown strings from being translated"`
+		MetricsFile             string        `flag:"metrics-file" vardefault:"metrics-file" default:"" description:"Write API calls, characters sent and processed strings per language as JSON into this file after translating"`
+		NoTranslateKeys         []string      `flag:"no-translate-keys" vardefault:"no-translate-keys" default:"" description:"Glob patterns of keys to copy verbatim from the reference instead of translating them"`
+		OutputDir               string        `flag:"output-dir" vardefault:"output-dir" default:"" description:"Write one <language>.json file per language into this directory (json output-format only)"`
+		OutputFile              string        `flag:"output-file,o" vardefault:"output-file" default:"../../src/langs/langs.js" description:"Where to put rendered translations (- for stdout), the extension of the default matches the output-format"`
+		OutputFormat            string        `flag:"output-format" vardefault:"output-format" default:"js" description:"Format of the rendered translations (go, js, json, ts)"`
+		PlaceholderRegex        string        `flag:"placeholder-regex" vardefault:"placeholder-regex" default:"\\{[^}]+\\}|%[sd]" description:"Regular expression matching interpolation tokens in the strings"`
+		PreserveFormatting      bool          `flag:"preserve-formatting" vardefault:"preserve-formatting" default:"false" description:"Deprecated: use deepl-preserve-formatting"`
+		ProtectPlaceholders     bool          `flag:"protect-placeholders" vardefault:"protect-placeholders" default:"false" description:"Wrap interpolation tokens into ignore tags to keep the translator from modifying them"`
+		Proxy                   string        `flag:"proxy" vardefault:"proxy" default:"" description:"HTTP(S) or SOCKS5 proxy to use for API requests (defaults to HTTP_PROXY / HTTPS_PROXY)"`
+		Prune                   bool          `flag:"prune" vardefault:"prune" default:"false" description:"Remove keys from translations which are not present in the reference"`
+		PruneUnused             bool          `flag:"prune-unused" vardefault:"prune-unused" default:"false" description:"Remove the keys not found by scan-usage from the reference and all languages"`
+		QuotaSafetyMargin       int64         `flag:"quota-safety-margin" vardefault:"quota-safety-margin" default:"0" description:"Number of characters to keep free of the translator quota"`
+		RateLimit               float64       `flag:"rate-limit" vardefault:"rate-limit" default:"0" description:"Maximum number of API requests per second across all workers, including retries (0 = unlimited)"`
+		ReferenceLanguage       string        `flag:"reference-language" vardefault:"reference-language" default:"" description:"Use this language of the translations as source for the run instead of the reference, translating only missing strings (translation file keeps its structure), reference of the file created by init"`
+		Report                  bool          `flag:"report" vardefault:"report" default:"false" description:"Report missing translations per language and exit"`
+		ReportFile              string        `flag:"report-file" vardefault:"report-file" default:"" description:"Write missing keys and completeness per language as JSON into this file (after translating or with check / report, statistics with stats)"`
+		SaveInterval            int           `flag:"save-interval" vardefault:"save-interval" default:"0" description:"Save the translation file after this many new translations (0 = after each language)"`
+		ScanUsage               string        `flag:"scan-usage" vardefault:"scan-usage" default:"" description:"Search the files in this directory for the reference keys, report the unused ones and exit"`
+		ShowUsage               bool          `flag:"show-usage" vardefault:"show-usage" default:"false" description:"Log character usage of the translator account before and after translating"`
+		SplitOutput             bool          `flag:"split-output" vardefault:"split-output" default:"false" description:"Write one file per language into the langs directory next to the output-file (js, json, ts output-format only)"`
+		SplitSentences          string        `flag:"split-sentences" vardefault:"split-sentences" default:"" description:"Deprecated: use deepl-split-sentences"`
+		Stats                   bool          `flag:"stats" vardefault:"stats" default:"false" description:"Print statistics about keys, completeness and character volume per language and exit (JSON into report-file if set)"`
+		Strict                  bool          `flag:"strict" vardefault:"strict" default:"false" description:"Fail when the reference contains empty strings instead of only warning about them"`
+		StrictPlaceholders      bool          `flag:"strict-placeholders" vardefault:"strict-placeholders" default:"false" description:"Fail when translations do not contain the placeholders of the reference"`
+		TagHandling             string        `flag:"tag-handling" vardefault:"tag-handling" default:"" description:"Deprecated: use deepl-tag-handling"`
+		TemplateFile            string        `flag:"template-file" vardefault:"template-file" default:"" description:"Load the output template from this file instead of using the built-in one (js, ts output-format only)"`
+		TranslationFile         string        `flag:"translation-file,t" vardefault:"translation-file" default:"../../i18n.yaml" description:"File to use for translations (- to read from stdin, not saved)"`
+		Translator              string        `flag:"translator" vardefault:"translator" default:"deepl" description:"Translation backend to use (deepl, libre, llm)"`
+		UsagePattern            string        `flag:"usage-pattern" vardefault:"usage-pattern" default:"\\$?\\bt[cm]?\\(\\s*['\"]([^'\"]+)['\"]" description:"Regular expression matching translation calls, the first group must contain the key (extract, find-unused, scan-usage only)"`
+		Verbose                 bool          `flag:"verbose,v" vardefault:"verbose" default:"false" description:"Include keys in reports"`
+		VerifySample            int           `flag:"verify-sample" vardefault:"verify-sample" default:"0" description:"Translate this many of the new strings per language back into the reference language and log the result for review"`
+		VersionAndExit          bool          `flag:"version" vardefault:"version" default:"false" description:"Prints current version and exits"`
+		Watch                   bool          `flag:"watch" vardefault:"watch" default:"false" description:"Watch the translation file and translate / render again on changes"`
 	}{}
 
+	// dryRunPending contains the paths per language which would have
+	// been translated when running in dry-run mode
+	dryRunPending = map[string][]string{}
+
+	// backupOnce ensures the translation file is backed up only before
+	// the first write of this invocation
+	backupOnce sync.Once
+
+	// httpClient is shared between all API requests to make use of
+	// connection pooling
+	httpClient *http.Client
+
+	// rateLimiter is shared by all workers to limit the requests (and
+	// their retries) sent to the translation APIs, nil if unlimited
+	rateLimiter *rate.Limiter
+
 	version = "dev"
 )
 
 func initApp() error {
 	rconfig.AutoEnv(true)
+
+	if configFile := configFileFromArgs(os.Args[1:]); configFile != "" {
+		defaults, err := loadConfigFile(configFile)
+		if err != nil {
+			return errors.Wrap(err, "loading config file")
+		}
+		rconfig.SetVariableDefaults(defaults)
+	}
+
 	if err := rconfig.ParseAndValidate(&cfg); err != nil {
 		return errors.Wrap(err, "parsing cli options")
 	}
@@ -67,9 +244,224 @@ func initApp() error {
 	}
 	logrus.SetLevel(l)
 
+	switch cfg.LogFormat {
+	case "text":
+		// Default formatter
+	case "json":
+		logrus.SetFormatter(&logrus.JSONFormatter{})
+	default:
+		return errors.Errorf("unknown log-format %q", cfg.LogFormat)
+	}
+
+	if _, ok := outputRenderers[cfg.OutputFormat]; !ok {
+		return errors.Errorf("unknown output-format %q", cfg.OutputFormat)
+	}
+
+	if cfg.OutputFile == defaultOutputFile {
+		cfg.OutputFile = strings.TrimSuffix(defaultOutputFile, path.Ext(defaultOutputFile)) + "." + cfg.OutputFormat
+	}
+
+	if cfg.OutputDir != "" && cfg.OutputFormat != "json" {
+		return errors.New("output-dir is only supported with json output-format")
+	}
+
+	if cfg.SplitOutput {
+		if _, ok := splitOutputTemplates[cfg.OutputFormat]; !ok && cfg.OutputFormat != "json" {
+			return errors.Errorf("split-output is not supported with %s output-format", cfg.OutputFormat)
+		}
+
+		if cfg.OutputDir != "" {
+			return errors.New("split-output and output-dir are mutually exclusive")
+		}
+	}
+
+	switch cfg.LangKeyStyle {
+	case langKeyStyleBCP47, langKeyStyleKeep, langKeyStyleUnderscore:
+	default:
+		return errors.Errorf("unknown lang-key-style %q", cfg.LangKeyStyle)
+	}
+
+	cfg.FallbackLanguage = normalizeLanguageKey(cfg.FallbackLanguage)
+	cfg.ReferenceLanguage = normalizeLanguageKey(cfg.ReferenceLanguage)
+	for i := range cfg.Languages {
+		cfg.Languages[i] = normalizeLanguageKey(cfg.Languages[i])
+	}
+
+	if cfg.Diff && cfg.OutputFile == stdioFilename {
+		return errors.New("diff cannot be written to stdout together with the output-file")
+	}
+
+	if cfg.ExportName != "" {
+		if cfg.OutputFormat != "js" && cfg.OutputFormat != "ts" {
+			return errors.Errorf("export-name is not supported with %s output-format", cfg.OutputFormat)
+		}
+
+		if cfg.SplitOutput {
+			return errors.New("export-name is not supported with split-output")
+		}
+
+		if !exportNameRegex.MatchString(cfg.ExportName) || cfg.ExportName == "locales" {
+			return errors.Errorf("invalid export-name %q", cfg.ExportName)
+		}
+	}
+
+	if cfg.TemplateFile != "" {
+		if cfg.OutputFormat != "js" && cfg.OutputFormat != "ts" {
+			return errors.Errorf("template-file is not supported with %s output-format", cfg.OutputFormat)
+		}
+
+		if cfg.SplitOutput {
+			return errors.New("template-file and split-output are mutually exclusive")
+		}
+
+		if customTemplate, err = loadTemplateFile(cfg.TemplateFile); err != nil {
+			return errors.Wrap(err, "loading template-file")
+		}
+	}
+
+	if cfg.DeeplAPIEndpoint == "" {
+		cfg.DeeplAPIEndpoint = deeplEndpointForKey(cfg.DeeplAPIKey)
+		if cfg.DeeplAPIKey != "" {
+			logrus.WithField("endpoint", cfg.DeeplAPIEndpoint).Info("selected DeepL API endpoint from API key")
+		}
+	}
+
+	if cfg.HTTPIdleConnsPerHost < 0 || cfg.HTTPMaxIdleConns < 0 || cfg.HTTPIdleTimeout < 0 {
+		return errors.New("http-idle-conns-per-host, http-idle-timeout and http-max-idle-conns must not be negative")
+	}
+
+	if httpClient, err = newHTTPClient(cfg.Proxy); err != nil {
+		return errors.Wrap(err, "creating HTTP client")
+	}
+
+	if cfg.SaveInterval < 0 {
+		return errors.New("save-interval must not be negative")
+	}
+
+	if cfg.Watch && cfg.TranslationFile == stdioFilename {
+		return errors.New("watch is not supported when reading from stdin")
+	}
+
+	if cfg.RateLimit < 0 {
+		return errors.New("rate-limit must not be negative")
+	}
+
+	if cfg.RateLimit > 0 {
+		rateLimiter = rate.NewLimiter(rate.Limit(cfg.RateLimit), 1)
+	}
+
+	if cfg.VerifySample < 0 {
+		return errors.New("verify-sample must not be negative")
+	}
+
+	if cfg.DeeplRequestTimeout <= 0 {
+		return errors.New("deepl-request-timeout must be positive")
+	}
+
+	if cfg.LibreRequestTimeout <= 0 {
+		return errors.New("libre-request-timeout must be positive")
+	}
+
+	if cfg.LLMRequestTimeout <= 0 {
+		return errors.New("llm-request-timeout must be positive")
+	}
+
+	if cfg.LLMMaxRetries < 0 {
+		return errors.New("llm-max-retries must not be negative")
+	}
+
+	if cfg.DeeplMaxRetries < 0 {
+		return errors.New("deepl-max-retries must not be negative")
+	}
+
+	if cfg.DocumentThreshold < 0 {
+		return errors.New("document-threshold must not be negative")
+	}
+
+	if cfg.DeeplPricePerMillion < 0 {
+		return errors.New("deepl-price-per-million must not be negative")
+	}
+
+	if cfg.Concurrency < 1 {
+		return errors.New("concurrency must be at least 1")
+	}
+
+	applyDeprecatedFlags()
+
+	switch cfg.DeeplSplitSentences {
+	case "", "0", "1", "nonewlines":
+	default:
+		return errors.Errorf("unknown deepl-split-sentences %q", cfg.DeeplSplitSentences)
+	}
+
+	if !isValidTagHandling(cfg.DeeplTagHandling) {
+		return errors.Errorf("unknown deepl-tag-handling %q", cfg.DeeplTagHandling)
+	}
+
+	if placeholderRegex, err = regexp.Compile(cfg.PlaceholderRegex); err != nil {
+		return errors.Wrap(err, "compiling placeholder-regex")
+	}
+
+	if usageRegex, err = regexp.Compile(cfg.UsagePattern); err != nil {
+		return errors.Wrap(err, "compiling usage-pattern")
+	}
+
+	if usageRegex.NumSubexp() < 1 {
+		return errors.New("usage-pattern must contain a group matching the key")
+	}
+
+	if cfg.PruneUnused && cfg.ScanUsage == "" {
+		return errors.New("prune-unused requires scan-usage")
+	}
+
+	if cfg.AddMissing && cfg.Extract == "" {
+		return errors.New("add-missing requires extract")
+	}
+
+	if protectRegex, err = compileProtectRegex(cfg.Markdown, cfg.ProtectPlaceholders, cfg.PlaceholderRegex); err != nil {
+		return errors.Wrap(err, "compiling protection patterns")
+	}
+
+	if cfg.CompletenessThreshold < 0 || cfg.CompletenessThreshold > 100 {
+		return errors.New("completeness-threshold must be in range 0-100")
+	}
+
 	return nil
 }
 
+// newHTTPClient creates the client shared by all API requests. Its
+// transport keeps enough idle connections for every worker to reuse
+// its connection to the API instead of opening a new one per request.
+func newHTTPClient(proxyURL string) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	transport.IdleConnTimeout = cfg.HTTPIdleTimeout
+	transport.MaxIdleConns = cfg.HTTPMaxIdleConns
+	transport.MaxIdleConnsPerHost = cfg.HTTPIdleConnsPerHost
+	if transport.MaxIdleConnsPerHost == 0 {
+		transport.MaxIdleConnsPerHost = cfg.Concurrency
+	}
+
+	if proxyURL != "" {
+		u, err := url.Parse(proxyURL)
+		if err != nil {
+			return nil, errors.Wrap(err, "parsing proxy")
+		}
+
+		switch u.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return nil, errors.Errorf("unsupported proxy scheme %q", u.Scheme)
+		}
+
+		// Credentials in the URL are sent as Proxy-Authorization
+		transport.Proxy = http.ProxyURL(u)
+		logrus.WithField("proxy", u.Redacted()).Debug("using proxy for API requests")
+	}
+
+	return &http.Client{Transport: transport}, nil
+}
+
 func main() {
 	var err error
 	if err = initApp(); err != nil {
@@ -81,6 +473,18 @@ func main() {
 		os.Exit(0)
 	}
 
+	if cfg.Init != "" {
+		if err = initTranslationFile(context.Background(), cfg.Init); err != nil {
+			logrus.WithError(err).Fatal("creating translation file")
+		}
+		return
+	}
+
+	if cfg.Watch {
+		watchTranslationFile()
+		return
+	}
+
 	logrus.Info("loading translations...")
 
 	tf, err := loadTranslationFile()
@@ -88,194 +492,1196 @@ func main() {
 		logrus.WithError(err).Fatal("loading translation file")
 	}
 
+	if problems := validateTranslationFile(tf); len(problems) > 0 {
+		for _, problem := range problems {
+			logrus.Error(problem)
+		}
+		logrus.WithField("count", len(problems)).Fatal("translation file is invalid")
+	}
+
+	if cfg.ReportFile != "" && (cfg.Check || cfg.Report) {
+		if err = writeReportFile(tf, cfg.ReportFile); err != nil {
+			logrus.WithError(err).Fatal("writing report file")
+		}
+	}
+
+	if cfg.Check {
+		if missing := checkMissingTranslations(tf); missing > 0 {
+			logrus.WithField("count", missing).Fatal("translations are missing")
+		}
+		logrus.Info("all translations present")
+		return
+	}
+
+	if cfg.Report {
+		reportMissingTranslations(tf, cfg.Verbose)
+		return
+	}
+
+	if cfg.Stats {
+		stats := calculateStats(tf)
+		logStats(tf, stats)
+
+		if cfg.ReportFile != "" {
+			if err = writeJSONFile(cfg.ReportFile, stats); err != nil {
+				logrus.WithError(err).Fatal("writing report file")
+			}
+		}
+		return
+	}
+
+	if cfg.AddLanguage != "" {
+		if err = addLanguage(context.Background(), &tf, cfg.AddLanguage); err != nil {
+			logrus.WithError(err).Fatal("adding language")
+		}
+
+		if err = saveTranslationFile(tf); err != nil {
+			logrus.WithError(err).Fatal("saving translation file")
+		}
+		return
+	}
+
+	if cfg.ClearReview != "" {
+		if err = clearReview(&tf, normalizeLanguageKey(cfg.ClearReview)); err != nil {
+			logrus.WithError(err).Fatal("clearing review markers")
+		}
+
+		if err = saveTranslationFile(tf); err != nil {
+			logrus.WithError(err).Fatal("saving translation file")
+		}
+		return
+	}
+
+	if cfg.Extract != "" {
+		if err = extractKeys(&tf, cfg.Extract); err != nil {
+			logrus.WithError(err).Fatal("extracting keys")
+		}
+
+		if cfg.AddMissing {
+			if err = saveTranslationFile(tf); err != nil {
+				logrus.WithError(err).Fatal("saving translation file")
+			}
+		}
+		return
+	}
+
+	if cfg.ScanUsage != "" {
+		unused, err := reportUnusedKeys(tf, cfg.ScanUsage)
+		if err != nil {
+			logrus.WithError(err).Fatal("reporting unused keys")
+		}
+
+		if cfg.PruneUnused && len(unused) > 0 {
+			removeKeys(&tf, unused)
+
+			if err = saveTranslationFile(tf); err != nil {
+				logrus.WithError(err).Fatal("saving translation file")
+			}
+		}
+		return
+	}
+
+	if cfg.FindUnused != "" {
+		unused, err := reportUnusedKeys(tf, cfg.FindUnused)
+		if err != nil {
+			logrus.WithError(err).Fatal("reporting unused keys")
+		}
+
+		if len(unused) > 0 {
+			logrus.WithField("count", len(unused)).Fatal("unused keys found")
+		}
+		logrus.Info("all keys are used")
+		return
+	}
+
+	if cfg.ExportXLIFF != "" {
+		if err = exportXLIFF(tf, cfg.ExportXLIFF); err != nil {
+			logrus.WithError(err).Fatal("exporting XLIFF")
+		}
+		return
+	}
+
+	if cfg.ExportPO != "" {
+		if err = exportPO(tf, cfg.ExportPO); err != nil {
+			logrus.WithError(err).Fatal("exporting PO")
+		}
+		return
+	}
+
+	if cfg.ExportCSV != "" {
+		if err = exportCSV(tf, cfg.ExportCSV); err != nil {
+			logrus.WithError(err).Fatal("exporting CSV")
+		}
+		return
+	}
+
+	if cfg.ImportCSV != "" {
+		if err = importCSV(&tf, cfg.ImportCSV); err != nil {
+			logrus.WithError(err).Fatal("importing CSV")
+		}
+
+		if err = saveTranslationFile(tf); err != nil {
+			logrus.WithError(err).Fatal("saving translation file")
+		}
+		return
+	}
+
+	if cfg.ImportPO != "" {
+		if err = importPO(&tf, cfg.ImportPO); err != nil {
+			logrus.WithError(err).Fatal("importing PO")
+		}
+
+		if err = saveTranslationFile(tf); err != nil {
+			logrus.WithError(err).Fatal("saving translation file")
+		}
+		return
+	}
+
+	if cfg.ImportXLIFF != "" {
+		if err = importXLIFF(&tf, cfg.ImportXLIFF); err != nil {
+			logrus.WithError(err).Fatal("importing XLIFF")
+		}
+
+		if err = saveTranslationFile(tf); err != nil {
+			logrus.WithError(err).Fatal("saving translation file")
+		}
+		return
+	}
+
+	if err = runTranslation(tf); err != nil {
+		logrus.WithError(err).Fatal("translating")
+	}
+}
+
+// runTranslation translates the missing strings of the loaded file,
+// saves it and renders the output
+func runTranslation(tf translationFile) error {
+	// Counters are per run when running repeatedly in watch mode
+	dryRunPending = map[string][]string{}
+	dedupCache.Reset()
+	summary.Reset()
+
+	t, err := getTranslatorByType(cfg.Translator)
+	if err != nil {
+		return errors.Wrap(err, "initializing translator")
+	}
+
+	// Cancel running translations on interrupt, completed translations
+	// are saved before exiting
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var usedBefore int64
+	if cfg.ShowUsage {
+		used, limit, err := fetchUsage(ctx, t)
+		if err != nil {
+			return errors.Wrap(err, "fetching usage")
+		}
+		logrus.WithFields(logrus.Fields{"used": used, "limit": limit}).Info("character usage before translating")
+		usedBefore = used
+	}
+
+	var before map[string]map[string]string
+	if cfg.Diff || cfg.DiffReport != "" {
+		before = translationStrings(tf)
+	}
+
+	if cfg.ReferenceLanguage != "" {
+		if err = tf.overrideReference(cfg.ReferenceLanguage); err != nil {
+			return errors.Wrap(err, "overriding reference")
+		}
+	}
+
 	logrus.Info("auto-translating new strings...")
 
-	if err = autoTranslate(&tf); err != nil {
-		logrus.WithError(err).Fatal("adding missing translations")
+	translateErr := autoTranslate(ctx, t, &tf)
+	tf = tf.withOriginalReference()
+
+	if cfg.ShowUsage && ctx.Err() == nil {
+		used, limit, err := fetchUsage(ctx, t)
+		if err != nil {
+			return errors.Wrap(err, "fetching usage")
+		}
+		logrus.WithFields(logrus.Fields{"used": used, "limit": limit, "consumed": used - usedBefore}).Info("character usage after translating")
+	}
+
+	// Restore default signal handling: a second interrupt while saving
+	// terminates immediately
+	stop()
+
+	var failures translationFailures
+	if translateErr != nil {
+		switch {
+		case errors.As(translateErr, &failures):
+			logrus.WithError(translateErr).Error("translations failed, saving progress")
+		case errors.Is(translateErr, errQuotaExceeded), errors.Is(translateErr, context.Canceled):
+			logrus.WithError(translateErr).Error("translation aborted, saving progress")
+		default:
+			return errors.Wrap(translateErr, "adding missing translations")
+		}
+	}
+
+	if cfg.Diff {
+		if err = writeDiff(os.Stdout, before, translationStrings(tf), dryRunPending); err != nil {
+			return errors.Wrap(err, "writing diff")
+		}
+	}
+
+	if cfg.DiffReport != "" {
+		if err = writeDiffReport(cfg.DiffReport, before, translationStrings(tf)); err != nil {
+			return errors.Wrap(err, "writing diff report")
+		}
+	}
+
+	if mismatches := validatePlaceholders(tf); mismatches > 0 && cfg.StrictPlaceholders {
+		return errors.Errorf("%d placeholder mismatches found", mismatches)
 	}
 
 	logrus.Info("saving translation file...")
 
 	if err = saveTranslationFile(tf); err != nil {
-		logrus.WithError(err).Fatal("saving translation file")
+		return errors.Wrap(err, "saving translation file")
+	}
+
+	completeness := calculateCompleteness(tf)
+
+	if cfg.ReportFile != "" {
+		if err = writeReportFile(tf, cfg.ReportFile); err != nil {
+			return errors.Wrap(err, "writing report file")
+		}
 	}
 
-	logrus.Info("updating JS embedded translations...")
+	logrus.Info("rendering translations...")
 
 	// Copy reference for rendering
 	tf.Translations[tf.Reference.LanguageKey] = &tf.Reference
 
-	if err = renderJSFile(tf); err != nil {
-		logrus.WithError(err).Fatal("rendering JS output")
+	render := outputRenderers[cfg.OutputFormat]
+	if cfg.SplitOutput {
+		render = renderSplitOutput
+	}
+
+	if cfg.Fallback {
+		tf = withFallbacks(tf)
 	}
+
+	if cfg.Flatten {
+		tf = flattened(tf)
+	}
+
+	if err = render(tf); err != nil {
+		return errors.Wrap(err, "rendering output")
+	}
+
+	if !cfg.DryRun {
+		summary.Log()
+
+		if cfg.MetricsFile != "" {
+			if err = summary.WriteMetrics(cfg.MetricsFile); err != nil {
+				return errors.Wrap(err, "writing metrics file")
+			}
+		}
+	}
+
+	if failures != nil {
+		failures.Log()
+		return errors.Wrap(failures, "translating")
+	}
+
+	if translateErr != nil {
+		return errors.New("translation was aborted before completion")
+	}
+
+	if langs := logCompleteness(completeness, cfg.CompletenessThreshold); len(langs) > 0 {
+		return errors.Errorf("translation completeness below threshold for %s", strings.Join(langs, ", "))
+	}
+
+	if cfg.DryRun {
+		var total int
+		for _, lang := range tf.Languages() {
+			if n := len(dryRunPending[lang]); n > 0 {
+				logrus.WithFields(logrus.Fields{"lang": lang, "count": n}).Info("dry-run: strings to translate")
+			}
+			total += len(dryRunPending[lang])
+		}
+
+		if total > 0 {
+			return errors.Errorf("dry-run: %d translations are missing", total)
+		}
+	}
+
+	return nil
 }
 
-func autoTranslate(tf *translationFile) error {
-	if cfg.DeeplAPIKey == "" {
-		logrus.Warn("missing DeepL API key, skipping translation of new strings")
-		return nil
+func autoTranslate(ctx context.Context, t translator, tf *translationFile) error {
+	if cfg.Prune {
+		pruneOrphanedKeys(tf)
 	}
 
-	// Collect keys to translate
-	var keys []string
-	for key := range tf.Reference.Translations {
-		keys = append(keys, key)
+	if empty := emptyReferenceStrings(*tf); len(empty) > 0 {
+		for _, path := range empty {
+			logrus.WithField("key", path).Warn("reference string is empty")
+		}
+
+		if cfg.Strict {
+			return errors.Errorf("%d empty reference strings found", len(empty))
+		}
+	}
+
+	var (
+		err       error
+		providers = newProviderSet(t)
+
+		langProvider  = make(map[string]string)
+		providerLangs = make(map[string][]string)
+		providerOrder []string
+	)
+
+	for _, lang := range tf.Languages() {
+		if !isSelectedLanguage(lang) {
+			logrus.WithField("lang", lang).Debug("language not selected, skipping")
+			continue
+		}
+
+		name, lt, err := providers.forLanguage(tf.Translations[lang])
+		if err != nil {
+			return errors.Wrapf(err, "initializing translator for %s", lang)
+		}
+
+		if name == "deepl" && cfg.DeeplAPIKey == "" && !cfg.DryRun {
+			logrus.WithField("lang", lang).Warn("missing DeepL API key, skipping translation of new strings")
+			continue
+		}
+
+		if lt.LanguageCode(lang, tf.Translations[lang]) == "" {
+			logrus.WithField("lang", lang).Warn("missing language code for translator, skipping")
+			continue
+		}
+
+		if providerLangs[name] == nil {
+			providerOrder = append(providerOrder, name)
+		}
+		providerLangs[name] = append(providerLangs[name], lang)
+		langProvider[lang] = name
+	}
+
+	for _, name := range providerOrder {
+		if err = validateSourceLanguage(ctx, providers.translators[name], tf); err != nil {
+			return errors.Wrapf(err, "validating source language for %s", name)
+		}
+
+		if err = validateTargetLanguages(ctx, providers.translators[name], tf, providerLangs[name]); err != nil {
+			return errors.Wrapf(err, "validating target languages for %s", name)
+		}
 	}
 
-	for lang := range tf.Translations {
-		if tf.Translations[lang].DeeplLanguage == "" {
-			logrus.WithField("lang", lang).Warn("missing DeepL language, skipping")
+	var (
+		abortErr error
+		estimate costEstimate
+		sample   verificationSample
+		tasks    []translationTask
+	)
+
+	for _, lang := range tf.Languages() {
+		name, ok := langProvider[lang]
+		if !ok {
 			continue
 		}
+		lt := providers.translators[name]
+
+		if err = validateGlossary(ctx, lt, tf, lang); err != nil {
+			return errors.Wrapf(err, "validating glossary for %s", lang)
+		}
+
+		langTasks, err := pendingTranslations(tf, lang)
+		if err != nil {
+			return errors.Wrapf(err, "collecting translations for %s", lang)
+		}
+
+		for i := range langTasks {
+			langTasks[i].translator = lt
+		}
+
+		unchanged := translatableStrings(tf, lang) - len(langTasks)
+		summary.update(lang, func(ls *languageSummary) { ls.Unchanged += unchanged })
+		estimate.Add(lang, name, langTasks)
 
-		for _, key := range keys {
-			if err := autoTranslateKeyForLang(tf, lang, key); err != nil {
-				return errors.Wrapf(err, "translating %s:%s", lang, key)
+		if cfg.DryRun {
+			for _, task := range langTasks {
+				recordDryRun(task)
 			}
+			continue
+		}
+
+		quota, err := providers.quota(ctx, name)
+		if err != nil {
+			return errors.Wrapf(err, "checking quota for %s", name)
+		}
+
+		if err = quota.Reserve(taskCharacters(langTasks)); err != nil {
+			// Translate the languages fitting the quota before aborting
+			abortErr = errors.Wrapf(err, "translating %s", lang)
+			break
+		}
+
+		sample.Pick(langTasks, cfg.VerifySample)
+		tasks = append(tasks, langTasks...)
+	}
+
+	estimate.Log()
+
+	checkpoint := func() error {
+		logrus.Debug("saving progress...")
+		return saveTranslationFile(*tf)
+	}
+
+	if err = runTranslationTasks(ctx, tf, tasks, cfg.Concurrency, cfg.SaveInterval, cfg.ContinueOnError, checkpoint); err != nil {
+		return err
+	}
+
+	sample.Log(ctx, tf, providers)
+
+	return abortErr
+}
+
+// validateTargetLanguages checks the language codes of the given
+// languages against the list of target languages supported by the
+// translator. Base languages (like "EN") are accepted if a variant is
+// supported.
+func validateTargetLanguages(ctx context.Context, t translator, tf *translationFile, langs []string) error {
+	tl, ok := t.(targetLanguageLister)
+	if !ok {
+		return nil
+	}
+
+	codes, err := tl.TargetLanguages(ctx)
+	if err != nil || codes == nil {
+		return errors.Wrap(err, "fetching target languages")
+	}
+
+	supported := make(map[string]bool)
+	for _, code := range codes {
+		supported[strings.ToUpper(code)] = true
+		supported[strings.ToUpper(strings.SplitN(code, "-", 2)[0])] = true
+	}
+
+	var invalid []string
+	for _, lang := range langs {
+		if code := t.LanguageCode(lang, tf.Translations[lang]); code != "" && !supported[strings.ToUpper(code)] {
+			invalid = append(invalid, fmt.Sprintf("%s (%s)", code, lang))
 		}
 	}
 
+	if len(invalid) > 0 {
+		return errors.Errorf(
+			"unsupported target languages: %s (supported: %s)",
+			strings.Join(invalid, ", "), strings.Join(codes, ", "),
+		)
+	}
+
 	return nil
 }
 
-func autoTranslateKeyForLang(tf *translationFile, lang, key string) (err error) {
-	if tf.Translations[lang].Translations[key] != nil {
-		// There is something, we assume that's fine, might miss out newly
-		// added strings in a slice - we care about that when we need to
+// validateSourceLanguage checks the language code of the reference
+// against the list of source languages supported by the translator
+func validateSourceLanguage(ctx context.Context, t translator, tf *translationFile) error {
+	sl, ok := t.(sourceLanguageLister)
+	if !ok {
 		return nil
 	}
 
-	logrus.WithFields(logrus.Fields{
-		"lang": lang,
-		"key":  key,
-	}).Info("fetching translation...")
+	codes, err := sl.SourceLanguages(ctx)
+	if err != nil || codes == nil {
+		return errors.Wrap(err, "fetching source languages")
+	}
+
+	code := t.LanguageCode(tf.Reference.LanguageKey, &tf.Reference)
+	for _, c := range codes {
+		if strings.EqualFold(c, code) {
+			return nil
+		}
+	}
+
+	return errors.Errorf(
+		"unsupported source language: %s (supported: %s)",
+		code, strings.Join(codes, ", "),
+	)
+}
+
+func validateGlossary(ctx context.Context, t translator, tf *translationFile, lang string) error {
+	glossaryID := tf.Translations[lang].GlossaryID
+	if glossaryID == "" {
+		return nil
+	}
 
-	if tf.Translations[lang].Translations == nil {
-		tf.Translations[lang].Translations = make(map[string]any)
+	gv, ok := t.(glossaryValidator)
+	if !ok {
+		return errors.Errorf("translator %q does not support glossaries", tf.Translations[lang].provider())
 	}
 
-	switch typedSrc := tf.Reference.Translations[key].(type) {
+	if cfg.DryRun {
+		// Validation needs API access which is not required for dry-run
+		logrus.WithField("lang", lang).Debug("dry-run: skipping glossary validation")
+		return nil
+	}
+
+	return gv.ValidateGlossary(
+		ctx,
+		glossaryID,
+		t.LanguageCode(tf.Reference.LanguageKey, &tf.Reference),
+		t.LanguageCode(lang, tf.Translations[lang]),
+	)
+}
+
+// pruneOrphanedKeys removes all keys (and their fingerprints) from the
+// selected languages which are no longer present in the reference
+func pruneOrphanedKeys(tf *translationFile) {
+	for lang, tm := range tf.Translations {
+		if !isSelectedLanguage(lang) {
+			continue
+		}
+
+		for key := range tm.Translations {
+			if tf.isSourceKey(key) {
+				continue
+			}
+
+			logrus.WithFields(logrus.Fields{
+				"lang": lang,
+				"key":  key,
+			}).Info("removing orphaned key")
+			delete(tm.Translations, key)
+		}
+
+		for path := range tm.Meta.SourceHashes {
+			if !tf.isSourceKey(strings.SplitN(path, ".", 2)[0]) {
+				delete(tm.Meta.SourceHashes, path)
+			}
+		}
+
+		for path := range tm.Meta.Provenance {
+			if !tf.isSourceKey(strings.SplitN(path, ".", 2)[0]) {
+				delete(tm.Meta.Provenance, path)
+			}
+		}
+	}
+}
+
+// pendingTranslations collects the strings of all reference keys which
+// need to be translated into the given language
+func pendingTranslations(tf *translationFile, lang string) ([]translationTask, error) {
+	var tasks []translationTask
+
+	for _, key := range tf.sourceKeys() {
+		if tf.isNoTranslateKey(key) {
+			copyVerbatim(tf, lang, key)
+			continue
+		}
+
+		keyTasks, err := pendingTranslationsForKey(tf, lang, key)
+		if err != nil {
+			return nil, errors.Wrapf(err, "checking %s", key)
+		}
+		tasks = append(tasks, keyTasks...)
+	}
+
+	return tasks, nil
+}
+
+func pendingTranslationsForKey(tf *translationFile, lang, key string) ([]translationTask, error) {
+	target := tf.Translations[lang]
+	return translationTasksForKey(tf, lang, key, func(path, src string, exists bool) bool {
+		if tf.override != nil {
+			return target.needsOverrideTranslation(path, exists)
+		}
+		return target.needsTranslation(lang, path, src, exists)
+	})
+}
+
+// translationTasksForKey creates tasks for all strings of the reference
+// key the filter returns true for
+func translationTasksForKey(tf *translationFile, lang, key string, filter taskFilter) ([]translationTask, error) {
+	// Tasks are created sequentially, the setter is called either here
+	// (for non-translatable values) or by storeTranslation holding the
+	// lock of the mapping
+	target := tf.Translations[lang]
+	if target.Translations == nil {
+		target.Translations = make(map[string]any)
+	}
+
+	srcLang, src := tf.sourceFor(key, lang)
+	if src == nil {
+		return nil, nil
+	}
+
+	tasks, err := pendingTranslationsForValue(
+		target, filter, lang, key,
+		src.Translations[key],
+		func() any { return target.Translations[key] },
+		func(value any) { target.Translations[key] = value },
+	)
+	for i := range tasks {
+		tasks[i].sourceLang, tasks[i].sourceMapping = srcLang, src
+	}
+
+	return tasks, err
+}
+
+// importTasks creates tasks for all strings of the language keyed by
+// their path to store externally translated values. Paths of keys not
+// to translate (they are copied from the reference) are marked verbatim.
+func importTasks(tf *translationFile, lang string) (tasks map[string]translationTask, verbatim map[string]bool, err error) {
+	tasks = make(map[string]translationTask)
+	verbatim = make(map[string]bool)
+
+	for _, key := range tf.ReferenceKeys() {
+		var keyTasks []translationTask
+		if keyTasks, err = translationTasksForKey(tf, lang, key, func(string, string, bool) bool { return true }); err != nil {
+			return nil, nil, errors.Wrapf(err, "collecting strings of %s", key)
+		}
+
+		for _, task := range keyTasks {
+			verbatim[task.Path] = tf.isNoTranslateKey(key)
+			tasks[task.Path] = task
+		}
+	}
+
+	return tasks, verbatim, nil
+}
+
+// pendingTranslationsForValue walks the reference value recursively and
+// collects tasks for all leaf strings needing translation. As the target
+// structure might not yet exist, it is accessed through get / set in order
+// to create intermediate lists and maps only when a translation is stored.
+func pendingTranslationsForValue(target *translationMapping, filter taskFilter, lang, path string, src any, get func() any, set func(any)) ([]translationTask, error) {
+	switch typedSrc := normalizeValue(src).(type) {
 	case string:
-		if tf.Translations[lang].Translations[key], err = fetchTranslation(
-			tf.Reference.DeeplLanguage,
-			tf.Translations[lang].DeeplLanguage,
-			typedSrc,
-		); err != nil {
-			return errors.Wrapf(err, "translating %s:%s", lang, key)
+		if !filter(path, typedSrc, get() != nil) {
+			return nil, nil
 		}
 
+		return []translationTask{{
+			Lang:   lang,
+			Path:   path,
+			Source: typedSrc,
+			apply:  func(value string) { set(value) },
+		}}, nil
+
 	case []any:
-		var ts []string
-		for _, str := range typedSrc {
-			tStr, err := fetchTranslation(
-				tf.Reference.DeeplLanguage,
-				tf.Translations[lang].DeeplLanguage,
-				str.(string),
+		var tasks []translationTask
+
+		for i := range typedSrc {
+			idx := i
+			elemTasks, err := pendingTranslationsForValue(
+				target, filter, lang, strings.Join([]string{path, strconv.Itoa(idx)}, "."),
+				typedSrc[idx],
+				func() any {
+					if list, _ := get().([]any); idx < len(list) {
+						return list[idx]
+					}
+					return nil
+				},
+				func(value any) {
+					list, _ := get().([]any)
+					list = resizeList(list, len(typedSrc))
+					list[idx] = value
+					set(list)
+				},
 			)
 			if err != nil {
-				return errors.Wrapf(err, "translating %s:%s", lang, key)
+				return nil, err
+			}
+			tasks = append(tasks, elemTasks...)
+		}
+
+		if existing, _ := get().([]any); len(existing) > len(typedSrc) {
+			// Reference list shrunk, drop surplus elements and their fingerprints
+			logrus.WithFields(logrus.Fields{
+				"lang": lang,
+				"key":  path,
+				"from": len(existing),
+				"to":   len(typedSrc),
+			}).Info("truncating list to reference length")
+
+			for i := len(typedSrc); i < len(existing); i++ {
+				target.deleteMeta(strings.Join([]string{path, strconv.Itoa(i)}, "."))
 			}
-			ts = append(ts, tStr)
+			set(existing[:len(typedSrc)])
+		}
+
+		return tasks, nil
+
+	case map[string]any:
+		var tasks []translationTask
+
+		for _, key := range sortedMapKeys(typedSrc) {
+			k := key
+			elemTasks, err := pendingTranslationsForValue(
+				target, filter, lang, strings.Join([]string{path, k}, "."),
+				typedSrc[k],
+				func() any {
+					m, _ := normalizeValue(get()).(map[string]any)
+					return m[k]
+				},
+				func(value any) {
+					m, _ := normalizeValue(get()).(map[string]any)
+					if m == nil {
+						m = make(map[string]any)
+					}
+					m[k] = value
+					set(m)
+				},
+			)
+			if err != nil {
+				return nil, err
+			}
+			tasks = append(tasks, elemTasks...)
+		}
+
+		return tasks, nil
+
+	case int, float64, bool:
+		// Not translatable, copy the value into the target
+		if get() != typedSrc {
+			logrus.WithFields(logrus.Fields{
+				"lang": lang,
+				"key":  path,
+			}).Debug("copying non-string value verbatim")
+			set(typedSrc)
 		}
-		tf.Translations[lang].Translations[key] = ts
+
+		return nil, nil
 
 	default:
-		return errors.Errorf("unexpected translation type %T", tf.Reference.Translations[key])
+		return nil, errors.Errorf("unexpected translation type %T at %q", src, path)
 	}
+}
 
-	return nil
+// normalizeValue converts nested maps decoded as translation into plain
+// maps for uniform type checks
+func normalizeValue(value any) any {
+	if m, ok := value.(translation); ok {
+		return map[string]any(m)
+	}
+	return value
+}
+
+func sortedMapKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+	return keys
 }
 
-func fetchTranslation(srcLang, destLang, text string) (string, error) {
-	params := url.Values{}
-	params.Set("text", text)
-	params.Set("source_lang", strings.ToUpper(srcLang))
-	params.Set("target_lang", strings.ToUpper(destLang))
-	params.Set("tag_handling", "html")
+// copyVerbatim puts a copy of the reference value into the target
+// language instead of translating it
+func copyVerbatim(tf *translationFile, lang, key string) {
+	logger := logrus.WithFields(logrus.Fields{
+		"lang": lang,
+		"key":  key,
+	})
+	logger.Debug("key matches no-translate pattern, using reference value")
+
+	target := tf.Translations[lang]
+	if reflect.DeepEqual(target.Translations[key], tf.Reference.Translations[key]) {
+		return
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), deeplRequestTimeout)
-	defer cancel()
+	logger.Info("copying untranslatable key verbatim")
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.DeeplAPIEndpoint, strings.NewReader(params.Encode()))
-	if err != nil {
-		return "", errors.Wrap(err, "creating request")
+	target.setTranslation(key, copyValue(tf.Reference.Translations[key]))
+	summary.update(lang, func(ls *languageSummary) { ls.Copied++ })
+}
+
+// copyValue creates a deep copy of the lists and maps within the value
+func copyValue(value any) any {
+	switch v := normalizeValue(value).(type) {
+	case []any:
+		out := make([]any, len(v))
+		for i := range v {
+			out[i] = copyValue(v[i])
+		}
+		return out
+
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k := range v {
+			out[k] = copyValue(v[k])
+		}
+		return out
+
+	default:
+		return v
+	}
+}
+
+func recordDryRun(task translationTask) {
+	logrus.WithFields(logrus.Fields{
+		"lang": task.Lang,
+		"key":  task.Path,
+	}).Info("dry-run: would fetch translation")
+	dryRunPending[task.Lang] = append(dryRunPending[task.Lang], task.Path)
+}
+
+// translateTask fetches the translation for a single reference string
+func translateTask(ctx context.Context, tf *translationFile, task translationTask) (string, error) {
+	t := task.translator
+
+	logrus.WithFields(logrus.Fields{
+		"lang":   task.Lang,
+		"key":    task.Path,
+		"source": task.sourceLang,
+	}).Debug("fetching translation...")
+
+	req := translationRequest{
+		SourceLang:  t.LanguageCode(task.sourceLang, task.sourceMapping),
+		TargetLang:  t.LanguageCode(task.Lang, tf.Translations[task.Lang]),
+		Text:        task.Source,
+		Description: tf.Reference.Descriptions[strings.SplitN(task.Path, ".", 2)[0]],
+		TagHandling: tf.tagHandling(task.Path),
 	}
-	req.Header.Set("Authorization", strings.Join([]string{"DeepL-Auth-Key", cfg.DeeplAPIKey}, " "))
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
-	resp, err := http.DefaultClient.Do(req)
+	if task.sourceMapping == &tf.Reference {
+		// Glossaries are bound to the language pair of the reference
+		req.GlossaryID = tf.Translations[task.Lang].GlossaryID
+	}
+
+	return fetchTranslation(ctx, task.Lang, t, req)
+}
+
+// fetchTranslation sends the request through the deduplication cache
+// and accounts the API call in the summary of the language
+func fetchTranslation(ctx context.Context, lang string, t translator, req translationRequest) (string, error) {
+	tStr, hit, err := dedupCache.Do(translationCacheKey(lang, req), func() (string, error) {
+		summary.update(lang, func(ls *languageSummary) {
+			ls.APICalls++
+			ls.Characters += int64(utf8.RuneCountInString(req.Text))
+		})
+		return sendTranslationRequest(ctx, t, req)
+	})
+	if hit {
+		summary.update(lang, func(ls *languageSummary) { ls.Deduplicated++ })
+	}
+
+	return tStr, err
+}
+
+// sendTranslationRequest protects the placeholders of the request, sends
+// it to the translator and restores the placeholders in the result
+func sendTranslationRequest(ctx context.Context, t translator, req translationRequest) (string, error) {
+	var tokens []string
+	if protectRegex != nil && req.TagHandling != tagHandlingOff {
+		req.Text, tokens = protectPlaceholders(req.Text)
+		req.IgnoreTags = []string{protectPlaceholderTag}
+	}
+
+	tStr, err := t.Translate(ctx, req)
 	if err != nil {
-		return "", errors.Wrap(err, "executing request")
+		return "", errors.Wrap(err, "fetching translation")
+	}
+
+	if tokens != nil {
+		tStr = restorePlaceholders(tStr, tokens)
+	}
+
+	return tStr, nil
+}
+
+// tagHandling returns the tag-handling to use for the string at the
+// given path, preferring the override of the reference key
+func (t translationFile) tagHandling(path string) string {
+	if th, ok := t.Reference.TagHandling[strings.SplitN(path, ".", 2)[0]]; ok {
+		return th
 	}
-	defer resp.Body.Close()
+	return cfg.DeeplTagHandling
+}
+
+// storeTranslation puts the translated value in place and records the
+// fingerprint of the source it was translated from (except with an
+// overridden reference, see needsOverrideTranslation)
+func storeTranslation(tf *translationFile, task translationTask, value string) {
+	target := tf.Translations[task.Lang]
+	target.locked(func() {
+		task.apply(value)
+		if tf.override == nil {
+			target.setSourceHash(task.Path, task.Source)
+		}
+		target.setProvenance(task.Path, provenanceAuto)
+	})
+	summary.update(task.Lang, func(ls *languageSummary) { ls.Translated++ })
+}
 
-	var payload struct {
-		Translations []struct {
-			Text string `json:"text"`
-		} `json:"translations"`
+// translatableStrings counts the strings in the reference and the
+// fallback references which are subject to translation into the given
+// language (not matching a no-translate pattern)
+func translatableStrings(tf *translationFile, lang string) (count int) {
+	for _, key := range tf.sourceKeys() {
+		if _, src := tf.sourceFor(key, lang); src != nil && !tf.isNoTranslateKey(key) {
+			count += len(leafStrings(key, src.Translations[key]))
+		}
 	}
+	return count
+}
 
-	if err = json.NewDecoder(resp.Body).Decode(&payload); err != nil {
-		return "", errors.Wrap(err, "decoding DeepL response")
+func taskCharacters(tasks []translationTask) (chars int64) {
+	for _, task := range tasks {
+		chars += int64(utf8.RuneCountInString(task.Source))
 	}
+	return chars
+}
 
-	if l := len(payload.Translations); l != 1 {
-		return "", errors.Errorf("unexpected number of translations: %d", l)
+// resizeList returns a copy of the list with the given length, keeping
+// the existing elements
+func resizeList(list []any, length int) []any {
+	if len(list) == length {
+		return list
 	}
 
-	return payload.Translations[0].Text, nil
+	out := make([]any, length)
+	copy(out, list)
+	return out
 }
 
 func loadTranslationFile() (translationFile, error) {
-	var tf translationFile
-	f, err := os.Open(cfg.TranslationFile)
-	if err != nil {
-		return tf, errors.Wrap(err, "opening translation file")
+	var (
+		tf translationFile
+		r  io.Reader = os.Stdin
+	)
+
+	if cfg.TranslationFile != stdioFilename {
+		f, err := os.Open(cfg.TranslationFile)
+		if err != nil {
+			return tf, errors.Wrap(err, "opening translation file")
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var root yaml.Node
+	if err := yaml.NewDecoder(r).Decode(&root); err != nil {
+		return tf, errors.Wrap(err, "parsing translation file")
 	}
-	defer f.Close()
 
-	return tf, errors.Wrap(yaml.NewDecoder(f).Decode(&tf), "decoding translation file")
+	if dups := duplicateKeys(&root, nil); len(dups) > 0 {
+		for _, dup := range dups {
+			logrus.Error(dup)
+		}
+		return tf, errors.Errorf("found %d duplicate keys", len(dups))
+	}
+
+	if err := root.Decode(&tf); err != nil {
+		return tf, errors.Wrap(err, "decoding translation file")
+	}
+	tf.source = &root
+
+	if err := normalizeLanguageKeys(&tf); err != nil {
+		return tf, errors.Wrap(err, "normalizing language keys")
+	}
+
+	for key, th := range tf.Reference.TagHandling {
+		if !isValidTagHandling(th) {
+			return tf, errors.Errorf("unknown tag-handling %q for key %q", th, key)
+		}
+	}
+
+	return tf, nil
 }
 
-func renderJSFile(tf translationFile) error {
-	tpl, err := template.New("js").Parse(jsTemplate)
+// saveTranslationFile writes the translation file in a stable form: the
+// YAML encoder emits map keys sorted and struct fields in declaration
+// order, so running twice on the same data yields identical bytes.
+// Comments of the loaded file are kept (see translationFileNode).
+func saveTranslationFile(tf translationFile) error {
+	if cfg.TranslationFile == stdioFilename {
+		logrus.Debug("translation file read from stdin, not saving")
+		return nil
+	}
+
+	if cfg.Backup && !cfg.DryRun {
+		var err error
+		backupOnce.Do(func() { err = backupTranslationFile() })
+		if err != nil {
+			return errors.Wrap(err, "backing up translation file")
+		}
+	}
+
+	node, err := translationFileNode(tf.withOriginalReference())
 	if err != nil {
-		return errors.Wrap(err, "parsing template")
+		return err
 	}
 
-	f, err := os.Create(cfg.OutputFile + ".tmp")
+	return writeFileAtomic(cfg.TranslationFile, func(w io.Writer) error {
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+
+		return errors.Wrap(encoder.Encode(node), "encoding translation file")
+	})
+}
+
+// backupTranslationFile copies the translation file to its .bak file,
+// a file not existing yet (as with init) has nothing to back up
+func backupTranslationFile() error {
+	src, err := os.Open(cfg.TranslationFile)
+	if os.IsNotExist(err) {
+		logrus.Debug("translation file does not exist yet, skipping backup")
+		return nil
+	}
 	if err != nil {
-		return errors.Wrap(err, "creating tempfile")
+		return errors.Wrap(err, "opening translation file")
 	}
+	defer src.Close()
+
+	return writeFileAtomic(cfg.TranslationFile+".bak", func(w io.Writer) error {
+		_, err := io.Copy(w, src)
+		return errors.Wrap(err, "copying translation file")
+	})
+}
+
+// leafStrings returns all strings contained in the value keyed by their
+// path: nested list elements and map entries are appended to the path of
+// their parent separated by a dot ("key.0", "key.other", "key.sub.title")
+func leafStrings(path string, value any) map[string]string {
+	leafs := make(map[string]string)
+
+	switch v := normalizeValue(value).(type) {
+	case string:
+		leafs[path] = v
+
+	case []any:
+		for i, elem := range v {
+			for p, str := range leafStrings(strings.Join([]string{path, strconv.Itoa(i)}, "."), elem) {
+				leafs[p] = str
+			}
+		}
 
-	if err = tpl.Execute(f, tf); err != nil {
-		f.Close()
-		return errors.Wrap(err, "rendering js template")
+	case map[string]any:
+		for key, elem := range v {
+			for p, str := range leafStrings(strings.Join([]string{path, key}, "."), elem) {
+				leafs[p] = str
+			}
+		}
 	}
 
-	f.Close()
-	return errors.Wrap(os.Rename(cfg.OutputFile+".tmp", cfg.OutputFile), "moving file in place")
+	return leafs
 }
 
-func saveTranslationFile(tf translationFile) error {
-	f, err := os.Create(cfg.TranslationFile + ".tmp")
-	if err != nil {
-		return errors.Wrap(err, "creating tempfile")
+// leafStrings returns all strings of the mapping keyed by their path
+// (see leafStrings)
+func (t *translationMapping) leafStrings() map[string]string {
+	leafs := make(map[string]string)
+	for key, value := range t.Translations {
+		for path, str := range leafStrings(key, value) {
+			leafs[path] = str
+		}
 	}
+	return leafs
+}
+
+// isNoTranslateKey checks the key against the glob patterns given in
+// the translation file and on the command line
+func (t translationFile) isNoTranslateKey(key string) bool {
+	var patterns []string
+	patterns = append(patterns, t.NoTranslate...)
+	patterns = append(patterns, t.DoNotTranslate...)
+	patterns = append(patterns, cfg.NoTranslateKeys...)
+
+	for _, pattern := range patterns {
+		if match, _ := path.Match(pattern, key); match {
+			return true
+		}
+	}
+	return false
+}
+
+// needsTranslation checks whether the string at the given path needs to
+// be (re-)translated: it is missing, the reference changed since it has
+// been translated or a re-translation is forced. Existing translations
+// without a stored fingerprint are assumed to be up-to-date and get the
+// fingerprint of the current reference. Reviewed translations are never
+// overwritten, if their reference changed they are reported as stale.
+func (t *translationMapping) needsTranslation(lang, path, src string, exists bool) bool {
+	if exists && t.isReviewed(path) {
+		if stored, ok := t.Meta.SourceHashes[path]; ok && stored != sourceHash(src) {
+			logrus.WithFields(logrus.Fields{
+				"lang": lang,
+				"key":  path,
+			}).Warn("reference changed since review, keeping stale translation")
+			summary.update(lang, func(ls *languageSummary) { ls.Stale++ })
+			return false
+		}
 
-	encoder := yaml.NewEncoder(f)
-	encoder.SetIndent(2)
+		logrus.WithField("key", path).Debug("skipping reviewed translation")
+		return false
+	}
+
+	if !exists || cfg.Force || isForcedRetranslation(path) {
+		return true
+	}
+
+	stored, ok := t.Meta.SourceHashes[path]
+	if !ok {
+		t.setSourceHash(path, src)
+		return false
+	}
+
+	return stored != sourceHash(src)
+}
 
-	if err = encoder.Encode(tf); err != nil {
-		f.Close()
-		return errors.Wrap(err, "encoding translation file")
+// needsOverrideTranslation replaces needsTranslation in runs with an
+// overridden reference: the fingerprints are those of the original
+// reference, so they are neither compared nor written and only missing
+// strings (or forced re-translations) are translated. The next run with
+// the original reference takes the new strings as up-to-date.
+func (t *translationMapping) needsOverrideTranslation(path string, exists bool) bool {
+	if exists && t.isReviewed(path) {
+		return false
 	}
+	return !exists || cfg.Force || isForcedRetranslation(path)
+}
 
-	f.Close()
-	return errors.Wrap(os.Rename(cfg.TranslationFile+".tmp", cfg.TranslationFile), "moving file in place")
+func (t *translationMapping) setSourceHash(path, src string) {
+	if t.Meta.SourceHashes == nil {
+		t.Meta.SourceHashes = make(map[string]string)
+	}
+	t.Meta.SourceHashes[path] = sourceHash(src)
+}
+
+// isForcedRetranslation checks the path and all of its parents against
+// the patterns given in the force-retranslate flag
+func isForcedRetranslation(p string) bool {
+	segments := strings.Split(p, ".")
+
+	for _, pattern := range cfg.ForceRetranslate {
+		for i := range segments {
+			if match, _ := path.Match(pattern, strings.Join(segments[:i+1], ".")); match {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// deleteMeta removes the fingerprints and provenance of the given path
+// and all paths nested below it
+func (t *translationMapping) deleteMeta(path string) {
+	for p := range t.Meta.SourceHashes {
+		if p == path || strings.HasPrefix(p, path+".") {
+			delete(t.Meta.SourceHashes, p)
+		}
+	}
+
+	for p := range t.Meta.Provenance {
+		if p == path || strings.HasPrefix(p, path+".") {
+			delete(t.Meta.Provenance, p)
+		}
+	}
 }
 
-func (t translation) ToJSON() (string, error) {
-	j, err := json.Marshal(t)
-	return strings.ReplaceAll(string(j), "'", "\\'"), errors.Wrap(err, "marshalling JSON")
+func sourceHash(src string) string {
+	h := sha256.Sum256([]byte(src))
+	return hex.EncodeToString(h[:sourceHashLength])
 }
@@ -0,0 +1,447 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Luzifer/rconfig/v2"
+)
+
+const testTranslationYAML = `reference:
+  deeplLanguage: EN
+  languageKey: en
+  translations:
+    greeting: Hello {name}
+    menu:
+      - Home
+      - About
+    nested:
+      title: Title
+      text: Text
+translations:
+  de:
+    deeplLanguage: DE
+    translations:
+      greeting: Hallo {name}
+  fr:
+    deeplLanguage: FR
+    translations: {}
+`
+
+// testConfig parses the given arguments as command line into cfg and
+// restores the previous configuration after the test. Translation and
+// output file are placed into a temporary directory.
+func testConfig(t *testing.T, args ...string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	savedCfg, savedArgs := cfg, os.Args
+	t.Cleanup(func() {
+		cfg, os.Args = savedCfg, savedArgs
+		backupOnce = sync.Once{}
+		customTemplate = ""
+		dryRunPending = map[string][]string{}
+		rconfig.SetVariableDefaults(map[string]string{})
+		summary.Reset()
+		dedupCache.Reset()
+	})
+
+	os.Args = append([]string{
+		"translate",
+		"--log-level=error",
+		"--translation-file=" + filepath.Join(dir, "i18n.yaml"),
+		"--output-file=" + filepath.Join(dir, "langs.js"),
+	}, args...)
+
+	if err := initApp(); err != nil {
+		t.Fatalf("initializing app: %s", err)
+	}
+}
+
+// loadTestFile writes the YAML into the translation-file and loads it
+func loadTestFile(t *testing.T, src string) translationFile {
+	t.Helper()
+
+	if err := os.WriteFile(cfg.TranslationFile, []byte(src), 0o600); err != nil {
+		t.Fatalf("writing translation file: %s", err)
+	}
+
+	tf, err := loadTranslationFile()
+	if err != nil {
+		t.Fatalf("loading translation file: %s", err)
+	}
+
+	return tf
+}
+
+// readTestFile returns the content of the file or fails the test
+func readTestFile(t *testing.T, filename string) string {
+	t.Helper()
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("reading %s: %s", filename, err)
+	}
+
+	return string(data)
+}
+
+func TestDryRunSkipsRemoteValidation(t *testing.T) {
+	testConfig(t, "--dry-run")
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected API request in dry-run: %s %s", r.Method, r.URL)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	tf := loadTestFile(t, testTranslationYAML)
+	tf.Translations["de"].GlossaryID = "glossary"
+
+	dt := newDeeplTranslator(srv.Client(), srv.URL+"/v2/translate", "", deeplOptions{})
+	if err := autoTranslate(context.Background(), dt, &tf); err != nil {
+		t.Fatalf("translating in dry-run: %s", err)
+	}
+
+	if len(dryRunPending["de"]) == 0 || len(dryRunPending["fr"]) == 0 {
+		t.Errorf("expected pending strings for de and fr, got %v", dryRunPending)
+	}
+}
+
+func TestRunTranslationIsStable(t *testing.T) {
+	testConfig(t, "--force-render")
+
+	// Keys and languages intentionally out of order
+	loadTestFile(t, `translations:
+  fr:
+    deeplLanguage: FR
+    translations:
+      b: B-fr
+      a: A-fr
+  de:
+    deeplLanguage: DE
+    translations:
+      b: B-de
+      a: A-de
+reference:
+  languageKey: en
+  deeplLanguage: EN
+  translations:
+    b: B
+    a: A
+`)
+
+	var yamlOut, jsOut []string
+	for i := 0; i < 2; i++ {
+		tf, err := loadTranslationFile()
+		if err != nil {
+			t.Fatalf("loading translation file: %s", err)
+		}
+
+		if err = runTranslation(tf); err != nil {
+			t.Fatalf("running translation: %s", err)
+		}
+
+		yamlOut = append(yamlOut, readTestFile(t, cfg.TranslationFile))
+		jsOut = append(jsOut, readTestFile(t, cfg.OutputFile))
+	}
+
+	if yamlOut[0] != yamlOut[1] {
+		t.Errorf("translation file differs between runs:\n%s\n---\n%s", yamlOut[0], yamlOut[1])
+	}
+	if jsOut[0] != jsOut[1] {
+		t.Errorf("output differs between runs:\n%s\n---\n%s", jsOut[0], jsOut[1])
+	}
+
+	if strings.Index(yamlOut[0], "\n  de:\n") > strings.Index(yamlOut[0], "\n  fr:\n") {
+		t.Errorf("languages are not sorted in translation file:\n%s", yamlOut[0])
+	}
+	if strings.Index(jsOut[0], "'de':") > strings.Index(jsOut[0], "'fr':") {
+		t.Errorf("languages are not sorted in output:\n%s", jsOut[0])
+	}
+	if !strings.Contains(jsOut[0], `'de': JSON.parse('{"a":"A-de","b":"B-de"}')`) {
+		t.Errorf("keys are not sorted in output:\n%s", jsOut[0])
+	}
+}
+
+func TestListTranslation(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		reference string
+		target    string
+		requested []string
+		expected  []any
+	}{
+		{
+			name:      "grow",
+			reference: "[One, Two, Three]",
+			target:    "[Eins]",
+			requested: []string{"EN>DE Two", "EN>DE Three"},
+			expected:  []any{"Eins", "DE:Two", "DE:Three"},
+		},
+		{
+			name:      "shrink",
+			reference: "[One]",
+			target:    "[Eins, Zwei, Drei]",
+			expected:  []any{"Eins"},
+		},
+		{
+			name:      "unchanged",
+			reference: "[One, Two]",
+			target:    "[Eins, Zwei]",
+			expected:  []any{"Eins", "Zwei"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			testConfig(t, "--deepl-api-key=test", "--concurrency=1")
+
+			ft := &fakeTranslator{}
+			tf := loadTestFile(t, "reference:\n  deeplLanguage: EN\n  translations:\n    list: "+tc.reference+
+				"\ntranslations:\n  de:\n    deeplLanguage: DE\n    translations:\n      list: "+tc.target+"\n")
+
+			if err := autoTranslate(context.Background(), ft, &tf); err != nil {
+				t.Fatalf("translating: %s", err)
+			}
+
+			if calls := ft.texts(); !reflect.DeepEqual(calls, tc.requested) {
+				t.Errorf("unexpected calls:\n got: %q\nwant: %q", calls, tc.requested)
+			}
+
+			if got := tf.Translations["de"].Translations["list"]; !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("unexpected list: %#v", got)
+			}
+
+			for path := range tf.Translations["de"].Meta.SourceHashes {
+				if _, ok := leafStrings("list", tc.expected)[path]; !ok {
+					t.Errorf("fingerprint of removed element %s was kept", path)
+				}
+			}
+		})
+	}
+}
+
+func TestTagHandlingOverride(t *testing.T) {
+	testConfig(t, "--deepl-tag-handling=xml")
+
+	tf := loadTestFile(t, "reference:\n  tagHandling:\n    plain: \"off\"\n  translations:\n    plain: a < b\n    markup: <b>x</b>\n")
+
+	for path, expected := range map[string]string{
+		"plain":   tagHandlingOff,
+		"plain.0": tagHandlingOff,
+		"markup":  tagHandlingXML,
+	} {
+		if th := tf.tagHandling(path); th != expected {
+			t.Errorf("tagHandling(%q) = %q, want %q", path, th, expected)
+		}
+	}
+}
+
+func TestGrowingReferenceListAcrossRuns(t *testing.T) {
+	testConfig(t, "--deepl-api-key=test", "--concurrency=1")
+
+	ft := &fakeTranslator{}
+	loadTestFile(t, "reference:\n  deeplLanguage: EN\n  translations:\n    list: [One, Two]\ntranslations:\n  de:\n    deeplLanguage: DE\n    translations: {}\n")
+
+	translate := func(reference []any) translationFile {
+		tf, err := loadTranslationFile()
+		if err != nil {
+			t.Fatalf("loading: %s", err)
+		}
+		tf.Reference.Translations["list"] = reference
+
+		if err = autoTranslate(context.Background(), ft, &tf); err != nil {
+			t.Fatalf("translating: %s", err)
+		}
+		if err = saveTranslationFile(tf); err != nil {
+			t.Fatalf("saving: %s", err)
+		}
+		return tf
+	}
+
+	translate([]any{"One", "Two"})
+
+	// Pretend a human fixed the first translation after the first run
+	tf, err := loadTranslationFile()
+	if err != nil {
+		t.Fatalf("loading: %s", err)
+	}
+	tf.Translations["de"].Translations["list"].([]any)[0] = "Eins"
+	if err = saveTranslationFile(tf); err != nil {
+		t.Fatalf("saving: %s", err)
+	}
+
+	ft.requests = nil
+	tf = translate([]any{"One", "Two", "Three", "Four"})
+
+	if calls := ft.texts(); !reflect.DeepEqual(calls, []string{"EN>DE Three", "EN>DE Four"}) {
+		t.Errorf("unexpected calls: %q", calls)
+	}
+
+	expected := []any{"Eins", "DE:Two", "DE:Three", "DE:Four"}
+	if got := tf.Translations["de"].Translations["list"]; !reflect.DeepEqual(got, expected) {
+		t.Errorf("unexpected list: %#v", got)
+	}
+
+	for i := 0; i < len(expected); i++ {
+		if _, ok := tf.Translations["de"].Meta.SourceHashes["list."+strconv.Itoa(i)]; !ok {
+			t.Errorf("missing fingerprint for list.%d", i)
+		}
+	}
+}
+
+func TestSaveTranslationFileIsSorted(t *testing.T) {
+	testConfig(t)
+
+	tf := translationFile{
+		Reference: translationMapping{
+			LanguageKey:  "en",
+			Translations: translation{"b": "B", "a": "A", "c": map[string]any{"z": "Z", "y": "Y"}},
+		},
+		Translations: map[string]*translationMapping{
+			"fr": {Translations: translation{"b": "B-fr", "a": "A-fr"}},
+			"de": {Translations: translation{"b": "B-de", "a": "A-de"}},
+		},
+	}
+
+	expected := `reference:
+  languageKey: en
+  translations:
+    a: A
+    b: B
+    c:
+      "y": "Y"
+      z: Z
+translations:
+  de:
+    translations:
+      a: A-de
+      b: B-de
+  fr:
+    translations:
+      a: A-fr
+      b: B-fr
+`
+
+	for i := 0; i < 2; i++ {
+		if err := saveTranslationFile(tf); err != nil {
+			t.Fatalf("saving: %s", err)
+		}
+
+		if saved := readTestFile(t, cfg.TranslationFile); saved != expected {
+			t.Fatalf("save %d produced unexpected output:\n%s", i+1, saved)
+		}
+	}
+}
+
+func TestProxy(t *testing.T) {
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = append(proxied, r.Method+" "+r.URL.String())
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer proxy.Close()
+
+	for _, tc := range []struct {
+		name    string
+		proxy   string
+		fail    bool
+		proxied bool
+	}{
+		{name: "no proxy"},
+		{name: "http proxy", proxy: proxy.URL, proxied: true},
+		{name: "socks5 proxy", proxy: "socks5://127.0.0.1:1080"},
+		{name: "unsupported scheme", proxy: "ftp://127.0.0.1", fail: true},
+		{name: "invalid URL", proxy: "http://[::1", fail: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			proxied = nil
+
+			client, err := newHTTPClient(tc.proxy)
+			if (err != nil) != tc.fail {
+				t.Fatalf("newHTTPClient(%q) = %v, want failure %v", tc.proxy, err, tc.fail)
+			}
+			if !tc.proxied {
+				return
+			}
+
+			resp, err := client.Get("http://api.example.invalid/v2/usage")
+			if err != nil {
+				t.Fatalf("requesting through proxy: %s", err)
+			}
+			resp.Body.Close()
+
+			if expected := []string{"GET http://api.example.invalid/v2/usage"}; !reflect.DeepEqual(proxied, expected) {
+				t.Errorf("proxy received %q, want %q", proxied, expected)
+			}
+		})
+	}
+
+	// The shared client is created from the proxy flag
+	proxied = nil
+	testConfig(t, "--proxy="+proxy.URL)
+
+	resp, err := httpClient.Get("http://api.example.invalid/v2/usage")
+	if err != nil {
+		t.Fatalf("requesting through proxy: %s", err)
+	}
+	resp.Body.Close()
+
+	if len(proxied) != 1 {
+		t.Errorf("shared client did not use the proxy flag")
+	}
+}
+
+func TestNeedsTranslation(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		args     []string
+		exists   bool
+		stored   string
+		reviewed bool
+		expected bool
+		// fingerprint is the source the stored fingerprint is of after
+		// the check
+		fingerprint string
+	}{
+		{name: "missing", expected: true},
+		{name: "up to date", exists: true, stored: "Hello", expected: false, fingerprint: "Hello"},
+		{name: "reference changed", exists: true, stored: "Hi", expected: true, fingerprint: "Hi"},
+		{name: "no fingerprint", exists: true, expected: false, fingerprint: "Hello"},
+		{name: "forced", args: []string{"--force"}, exists: true, stored: "Hello", expected: true, fingerprint: "Hello"},
+		{name: "forced by pattern", args: []string{"--force-retranslate=greet*"}, exists: true, stored: "Hello", expected: true, fingerprint: "Hello"},
+		{name: "reviewed and changed", exists: true, stored: "Hi", reviewed: true, expected: false, fingerprint: "Hi"},
+		{name: "reviewed and forced", args: []string{"--force"}, exists: true, stored: "Hello", reviewed: true, expected: false, fingerprint: "Hello"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			testConfig(t, tc.args...)
+
+			tm := &translationMapping{}
+			if tc.stored != "" {
+				tm.setSourceHash("greeting", tc.stored)
+			}
+			if tc.reviewed {
+				tm.setProvenance("greeting", provenanceReviewed)
+			}
+
+			if got := tm.needsTranslation("de", "greeting", "Hello", tc.exists); got != tc.expected {
+				t.Errorf("needsTranslation() = %v, want %v", got, tc.expected)
+			}
+
+			var want map[string]string
+			if tc.fingerprint != "" {
+				want = map[string]string{"greeting": sourceHash(tc.fingerprint)}
+			}
+			if got := tm.Meta.SourceHashes; !reflect.DeepEqual(got, want) {
+				t.Errorf("fingerprints = %v, want %v", got, want)
+			}
+		})
+	}
+}
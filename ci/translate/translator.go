@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	tagHandlingHTML = "html"
+	tagHandlingOff  = "off"
+	tagHandlingXML  = "xml"
+)
+
+type (
+	translator interface {
+		// LanguageCode returns the code of the language to pass to the
+		// translation backend or an empty string if the language is not
+		// configured for the backend
+		LanguageCode(lang string, tm *translationMapping) string
+		Translate(ctx context.Context, req translationRequest) (string, error)
+	}
+
+	// glossaryValidator is implemented by translators supporting
+	// glossaries to ensure the glossary matches the language pair
+	glossaryValidator interface {
+		ValidateGlossary(ctx context.Context, glossaryID, srcLang, destLang string) error
+	}
+
+	// sourceLanguageLister is implemented by translators able to list
+	// the language codes they can translate from. A nil list means the
+	// list is not available and no validation should happen.
+	sourceLanguageLister interface {
+		SourceLanguages(ctx context.Context) ([]string, error)
+	}
+
+	// targetLanguageLister is implemented by translators able to list
+	// the language codes they can translate into. A nil list means the
+	// list is not available and no validation should happen.
+	targetLanguageLister interface {
+		TargetLanguages(ctx context.Context) ([]string, error)
+	}
+
+	// usageReporter is implemented by translators able to report the
+	// consumed and available characters of the account
+	usageReporter interface {
+		Usage(ctx context.Context) (used, limit int64, err error)
+	}
+
+	// translatorOptions contains the settings shared by all translators
+	// talking to an HTTP API
+	translatorOptions struct {
+		// MaxRetries is the number of times a request failing with a
+		// transient status (429, 5xx) is repeated
+		MaxRetries int
+		// RequestTimeout limits the duration of every single request
+		// to the API
+		RequestTimeout time.Duration
+	}
+
+	translationRequest struct {
+		SourceLang string
+		TargetLang string
+		Text       string
+		// Description is an optional note about where and how the text
+		// is used, it is passed to the backend but not translated
+		Description string
+		// GlossaryID references a glossary to use for the translation
+		// if supported by the backend
+		GlossaryID string
+		// TagHandling defines how markup in the text is treated: "html",
+		// "xml" or "off" to handle the text as plain text
+		TagHandling string
+		// IgnoreTags lists tags whose content must not be translated
+		IgnoreTags []string
+	}
+)
+
+func isValidTagHandling(th string) bool {
+	switch th {
+	case tagHandlingHTML, tagHandlingXML, tagHandlingOff:
+		return true
+	default:
+		return false
+	}
+}
+
+func getTranslatorByType(t string) (translator, error) {
+	switch t {
+	case "deepl":
+		return newDeeplTranslator(httpClient, cfg.DeeplAPIEndpoint, cfg.DeeplAPIKey, deeplOptions{
+			DecodeEntities:     cfg.DecodeEntities,
+			DocumentThreshold:  cfg.DocumentThreshold,
+			PreserveFormatting: cfg.DeeplPreserveFormatting,
+			SplitSentences:     cfg.DeeplSplitSentences,
+			translatorOptions: translatorOptions{
+				MaxRetries:     cfg.DeeplMaxRetries,
+				RequestTimeout: cfg.DeeplRequestTimeout,
+			},
+		}), nil
+	case "libre":
+		return newLibreTranslator(httpClient, cfg.LibreEndpoint, cfg.LibreAPIKey, translatorOptions{
+			RequestTimeout: cfg.LibreRequestTimeout,
+		})
+	case "llm":
+		return newLLMTranslator(httpClient, cfg.LLMEndpoint, cfg.LLMModel, cfg.LLMAPIKey, translatorOptions{
+			MaxRetries:     cfg.LLMMaxRetries,
+			RequestTimeout: cfg.LLMRequestTimeout,
+		})
+	default:
+		return nil, errors.Errorf("translator type %q not found", t)
+	}
+}
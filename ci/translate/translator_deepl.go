@@ -0,0 +1,318 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"html"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	deeplFreeEndpoint  = "https://api-free.deepl.com/v2/translate"
+	deeplFreeKeySuffix = ":fx"
+	deeplProEndpoint   = "https://api.deepl.com/v2/translate"
+	// deeplDefaultRequestTimeout is used if no timeout is configured
+	deeplDefaultRequestTimeout = 10 * time.Second
+)
+
+// htmlEntityRegex matches named and numeric character references
+var htmlEntityRegex = regexp.MustCompile(`&(?:#[0-9]+|#[xX][0-9a-fA-F]+|[A-Za-z][A-Za-z0-9]*);`)
+
+type (
+	deeplTranslator struct {
+		apiEndpoint string
+		apiKey      string
+		client      *http.Client
+		opts        deeplOptions
+	}
+
+	// deeplOptions contains optional request parameters sent with every
+	// translation request
+	deeplOptions struct {
+		translatorOptions
+
+		// DecodeEntities decodes HTML entities in translations using
+		// html tag-handling which are not present in the source
+		DecodeEntities bool
+		// DocumentThreshold is the size in bytes above which texts are
+		// translated as documents, disabled if zero
+		DocumentThreshold int
+		// PreserveFormatting sets preserve_formatting=1 if enabled
+		PreserveFormatting bool
+		// SplitSentences is passed as split_sentences parameter if set
+		SplitSentences string
+	}
+)
+
+func newDeeplTranslator(client *http.Client, apiEndpoint, apiKey string, opts deeplOptions) translator {
+	if opts.RequestTimeout <= 0 {
+		opts.RequestTimeout = deeplDefaultRequestTimeout
+	}
+
+	return &deeplTranslator{
+		apiEndpoint: apiEndpoint,
+		apiKey:      apiKey,
+		client:      client,
+		opts:        opts,
+	}
+}
+
+// deeplEndpointForKey selects the API endpoint matching the account
+// type: keys of free accounts end in ":fx"
+func deeplEndpointForKey(apiKey string) string {
+	if strings.HasSuffix(apiKey, deeplFreeKeySuffix) {
+		return deeplFreeEndpoint
+	}
+	return deeplProEndpoint
+}
+
+func (deeplTranslator) LanguageCode(_ string, tm *translationMapping) string {
+	return tm.DeeplLanguage
+}
+
+func (d deeplTranslator) Translate(ctx context.Context, tr translationRequest) (string, error) {
+	var (
+		text string
+		err  error
+	)
+
+	if d.opts.DocumentThreshold > 0 && len(tr.Text) > d.opts.DocumentThreshold {
+		text, err = d.translateDocument(ctx, tr)
+	} else {
+		text, err = d.translateText(ctx, tr)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if d.opts.DecodeEntities && tr.TagHandling == tagHandlingHTML {
+		text = decodeAddedEntities(text, tr.Text)
+	}
+
+	return text, nil
+}
+
+// translateText translates the text using the inline text endpoint
+func (d deeplTranslator) translateText(ctx context.Context, tr translationRequest) (string, error) {
+	params := url.Values{}
+	params.Set("text", tr.Text)
+	params.Set("source_lang", deeplSourceLanguage(tr.SourceLang))
+	params.Set("target_lang", strings.ToUpper(tr.TargetLang))
+	if tr.TagHandling != tagHandlingOff {
+		params.Set("tag_handling", tr.TagHandling)
+	}
+
+	if d.opts.PreserveFormatting {
+		params.Set("preserve_formatting", "1")
+	}
+
+	if d.opts.SplitSentences != "" {
+		params.Set("split_sentences", d.opts.SplitSentences)
+	}
+
+	if len(tr.IgnoreTags) > 0 {
+		params.Set("ignore_tags", strings.Join(tr.IgnoreTags, ","))
+	}
+
+	if tr.Description != "" {
+		// The context is not translated and does not count towards
+		// the billed characters
+		params.Set("context", tr.Description)
+	}
+
+	if tr.GlossaryID != "" {
+		params.Set("glossary_id", tr.GlossaryID)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, d.opts.RequestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.apiEndpoint, strings.NewReader(params.Encode()))
+	if err != nil {
+		return "", errors.Wrap(err, "creating request")
+	}
+	req.Header.Set("Authorization", strings.Join([]string{"DeepL-Auth-Key", d.apiKey}, " "))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := d.do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.Errorf("unexpected HTTP status %d", resp.StatusCode)
+	}
+
+	var payload struct {
+		Translations []struct {
+			Text string `json:"text"`
+		} `json:"translations"`
+	}
+
+	if err = json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return "", errors.Wrap(err, "decoding DeepL response")
+	}
+
+	if l := len(payload.Translations); l != 1 {
+		return "", errors.Errorf("unexpected number of translations: %d", l)
+	}
+
+	return payload.Translations[0].Text, nil
+}
+
+func (d deeplTranslator) ValidateGlossary(ctx context.Context, glossaryID, srcLang, destLang string) error {
+	glossaryURL, err := d.apiURL("glossaries/" + url.PathEscape(glossaryID))
+	if err != nil {
+		return errors.Wrap(err, "building glossary URL")
+	}
+
+	var glossary struct {
+		SourceLang string `json:"source_lang"`
+		TargetLang string `json:"target_lang"`
+	}
+
+	if err = d.apiGet(ctx, glossaryURL, &glossary); err != nil {
+		return errors.Wrap(err, "fetching glossary")
+	}
+
+	if !deeplLanguageMatches(glossary.SourceLang, srcLang) || !deeplLanguageMatches(glossary.TargetLang, destLang) {
+		return errors.Errorf(
+			"glossary %s translates %s to %s but %s to %s is configured",
+			glossaryID, glossary.SourceLang, glossary.TargetLang, srcLang, destLang,
+		)
+	}
+
+	return nil
+}
+
+func (d deeplTranslator) SourceLanguages(ctx context.Context) ([]string, error) {
+	return d.languages(ctx, "source")
+}
+
+func (d deeplTranslator) TargetLanguages(ctx context.Context) ([]string, error) {
+	return d.languages(ctx, "target")
+}
+
+// languages lists the codes of the source or target languages, nil is
+// returned without API key as the endpoint cannot be queried
+func (d deeplTranslator) languages(ctx context.Context, typ string) ([]string, error) {
+	if d.apiKey == "" {
+		return nil, nil
+	}
+
+	languagesURL, err := d.apiURL("languages?type=" + typ)
+	if err != nil {
+		return nil, errors.Wrap(err, "building languages URL")
+	}
+
+	var languages []struct {
+		Language string `json:"language"`
+	}
+
+	if err = d.apiGet(ctx, languagesURL, &languages); err != nil {
+		return nil, errors.Wrap(err, "querying languages endpoint")
+	}
+
+	codes := make([]string, 0, len(languages))
+	for _, l := range languages {
+		codes = append(codes, l.Language)
+	}
+
+	return codes, nil
+}
+
+func (d deeplTranslator) Usage(ctx context.Context) (used, limit int64, err error) {
+	usageURL, err := d.apiURL("usage")
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "building usage URL")
+	}
+
+	var usage struct {
+		CharacterCount int64 `json:"character_count"`
+		CharacterLimit int64 `json:"character_limit"`
+	}
+
+	if err = d.apiGet(ctx, usageURL, &usage); err != nil {
+		return 0, 0, errors.Wrap(err, "querying usage endpoint")
+	}
+
+	return usage.CharacterCount, usage.CharacterLimit, nil
+}
+
+// apiGet executes a GET request against the DeepL API and decodes the
+// JSON response into the given target
+func (d deeplTranslator) apiGet(ctx context.Context, apiURL string, target any) error {
+	ctx, cancel := context.WithTimeout(ctx, d.opts.RequestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
+	if err != nil {
+		return errors.Wrap(err, "creating request")
+	}
+	req.Header.Set("Authorization", strings.Join([]string{"DeepL-Auth-Key", d.apiKey}, " "))
+
+	resp, err := d.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("unexpected HTTP status %d", resp.StatusCode)
+	}
+
+	return errors.Wrap(json.NewDecoder(resp.Body).Decode(target), "decoding DeepL response")
+}
+
+// do executes the request against the DeepL API, see doRequest
+func (d deeplTranslator) do(req *http.Request) (*http.Response, error) {
+	return doRequest(d.client, req, d.opts.translatorOptions, "deepl-request-timeout")
+}
+
+// apiURL resolves the given path relative to the configured endpoint:
+// "usage" on https://api.deepl.com/v2/translate yields
+// https://api.deepl.com/v2/usage
+func (d deeplTranslator) apiURL(path string) (string, error) {
+	base, err := url.Parse(d.apiEndpoint)
+	if err != nil {
+		return "", errors.Wrap(err, "parsing API endpoint")
+	}
+
+	ref, err := url.Parse(path)
+	if err != nil {
+		return "", errors.Wrap(err, "parsing path")
+	}
+
+	return base.ResolveReference(ref).String(), nil
+}
+
+// deeplSourceLanguage returns the code to send as source language,
+// DeepL accepts only base languages ("PT", not "PT-BR") as source
+func deeplSourceLanguage(code string) string {
+	return strings.ToUpper(strings.SplitN(code, "-", 2)[0])
+}
+
+// deeplLanguageMatches compares the language codes ignoring case and
+// regional variants as glossaries are defined on the base language
+func deeplLanguageMatches(a, b string) bool {
+	return strings.EqualFold(strings.SplitN(a, "-", 2)[0], strings.SplitN(b, "-", 2)[0])
+}
+
+// decodeAddedEntities decodes the entities in the translation which are
+// not contained in the source: they were added by DeepL while entities
+// given in the source are meant to stay encoded
+func decodeAddedEntities(text, source string) string {
+	return htmlEntityRegex.ReplaceAllStringFunc(text, func(entity string) string {
+		if strings.Contains(source, entity) {
+			return entity
+		}
+		return html.UnescapeString(entity)
+	})
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	deeplDocumentMaxPollInterval = 30 * time.Second
+	deeplDocumentMinPollInterval = time.Second
+)
+
+// deeplDocument identifies an uploaded document at the DeepL API
+type deeplDocument struct {
+	ID  string `json:"document_id"`
+	Key string `json:"document_key"`
+}
+
+// translateDocument translates the text through the document API: it
+// is uploaded as HTML (or plain text if tag-handling is off), the status
+// is polled until the translation is done and the result is downloaded.
+// Ignore tags and the context are not supported by the document API.
+func (d deeplTranslator) translateDocument(ctx context.Context, tr translationRequest) (string, error) {
+	doc, err := d.uploadDocument(ctx, tr)
+	if err != nil {
+		return "", errors.Wrap(err, "uploading document")
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"document_id": doc.ID,
+		"size":        len(tr.Text),
+	}).Debug("translating text as document")
+
+	if err = d.waitForDocument(ctx, doc); err != nil {
+		return "", errors.Wrap(err, "waiting for document translation")
+	}
+
+	text, err := d.downloadDocument(ctx, doc)
+	return text, errors.Wrap(err, "downloading document")
+}
+
+func (d deeplTranslator) uploadDocument(ctx context.Context, tr translationRequest) (doc deeplDocument, err error) {
+	filename := "text.html"
+	if tr.TagHandling == tagHandlingOff {
+		filename = "text.txt"
+	}
+
+	body := new(bytes.Buffer)
+	mw := multipart.NewWriter(body)
+
+	fields := map[string]string{
+		"source_lang": deeplSourceLanguage(tr.SourceLang),
+		"target_lang": strings.ToUpper(tr.TargetLang),
+		"glossary_id": tr.GlossaryID,
+	}
+	for name, value := range fields {
+		if value == "" {
+			continue
+		}
+		if err = mw.WriteField(name, value); err != nil {
+			return doc, errors.Wrapf(err, "writing field %s", name)
+		}
+	}
+
+	fw, err := mw.CreateFormFile("file", filename)
+	if err != nil {
+		return doc, errors.Wrap(err, "creating file field")
+	}
+	if _, err = io.WriteString(fw, tr.Text); err != nil {
+		return doc, errors.Wrap(err, "writing file field")
+	}
+	if err = mw.Close(); err != nil {
+		return doc, errors.Wrap(err, "closing multipart body")
+	}
+
+	resp, err := d.documentRequest(ctx, "document", mw.FormDataContentType(), body)
+	if err != nil {
+		return doc, err
+	}
+	defer resp.Body.Close()
+
+	if err = json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return doc, errors.Wrap(err, "decoding DeepL response")
+	}
+
+	return doc, nil
+}
+
+// waitForDocument polls the status of the document until it has been
+// translated, using the remaining time reported by DeepL as interval
+func (d deeplTranslator) waitForDocument(ctx context.Context, doc deeplDocument) error {
+	for {
+		resp, err := d.documentRequest(ctx, "document/"+url.PathEscape(doc.ID), "application/x-www-form-urlencoded",
+			strings.NewReader(url.Values{"document_key": {doc.Key}}.Encode()))
+		if err != nil {
+			return err
+		}
+
+		var status struct {
+			Status           string `json:"status"`
+			SecondsRemaining int    `json:"seconds_remaining"`
+			ErrorMessage     string `json:"error_message"`
+		}
+
+		err = json.NewDecoder(resp.Body).Decode(&status)
+		resp.Body.Close()
+		if err != nil {
+			return errors.Wrap(err, "decoding DeepL response")
+		}
+
+		switch status.Status {
+		case "done":
+			return nil
+		case "error":
+			return errors.Errorf("document translation failed: %s", status.ErrorMessage)
+		}
+
+		wait := time.Duration(status.SecondsRemaining) * time.Second
+		switch {
+		case wait < deeplDocumentMinPollInterval:
+			wait = deeplDocumentMinPollInterval
+		case wait > deeplDocumentMaxPollInterval:
+			wait = deeplDocumentMaxPollInterval
+		}
+
+		select {
+		case <-ctx.Done():
+			return errors.Wrap(ctx.Err(), "waiting for status")
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (d deeplTranslator) downloadDocument(ctx context.Context, doc deeplDocument) (string, error) {
+	resp, err := d.documentRequest(ctx, "document/"+url.PathEscape(doc.ID)+"/result", "application/x-www-form-urlencoded",
+		strings.NewReader(url.Values{"document_key": {doc.Key}}.Encode()))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	text, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", errors.Wrap(err, "reading document")
+	}
+
+	return string(text), nil
+}
+
+// documentRequest executes a POST request against the document API,
+// the caller must close the body of the returned response
+func (d deeplTranslator) documentRequest(ctx context.Context, path, contentType string, body io.Reader) (*http.Response, error) {
+	apiURL, err := d.apiURL(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "building document URL")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, d.opts.RequestTimeout)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL, body)
+	if err != nil {
+		cancel()
+		return nil, errors.Wrap(err, "creating request")
+	}
+	req.Header.Set("Authorization", strings.Join([]string{"DeepL-Auth-Key", d.apiKey}, " "))
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := d.do(req)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		cancel()
+		return nil, errors.Errorf("unexpected HTTP status %d", resp.StatusCode)
+	}
+
+	resp.Body = cancelOnClose{resp.Body, cancel}
+	return resp, nil
+}
+
+// cancelOnClose releases the request context when the body is closed
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c cancelOnClose) Close() error {
+	defer c.cancel()
+	return c.ReadCloser.Close()
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+)
+
+// deeplTestAPI is a fake DeepL API recording the form of every
+// translation request, it answers with the text prefixed by the target
+// language unless a response is set
+type deeplTestAPI struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	forms    []url.Values
+	response string
+}
+
+func newDeeplTestAPI(t *testing.T) *deeplTestAPI {
+	t.Helper()
+
+	api := &deeplTestAPI{}
+	api.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("parsing form: %s", err)
+		}
+
+		api.mu.Lock()
+		api.forms = append(api.forms, r.PostForm)
+		text := api.response
+		api.mu.Unlock()
+
+		if text == "" {
+			text = r.PostForm.Get("target_lang") + ":" + r.PostForm.Get("text")
+		}
+
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"translations": []map[string]string{{"text": text}},
+		})
+	}))
+	t.Cleanup(api.Close)
+
+	return api
+}
+
+// translator returns a DeepL translator talking to the fake API
+func (a *deeplTestAPI) translator(opts deeplOptions) translator {
+	return newDeeplTranslator(a.Client(), a.URL+"/v2/translate", "test", opts)
+}
+
+// lastForm returns the form of the last translation request
+func (a *deeplTestAPI) lastForm(t *testing.T) url.Values {
+	t.Helper()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if len(a.forms) == 0 {
+		t.Fatal("no request received")
+	}
+	return a.forms[len(a.forms)-1]
+}
+
+func TestDeeplTagHandling(t *testing.T) {
+	api := newDeeplTestAPI(t)
+	dt := api.translator(deeplOptions{})
+
+	for _, tc := range []struct {
+		tagHandling string
+		expected    string
+		present     bool
+	}{
+		{tagHandlingHTML, "html", true},
+		{tagHandlingXML, "xml", true},
+		{tagHandlingOff, "", false},
+	} {
+		t.Run(tc.tagHandling, func(t *testing.T) {
+			if _, err := dt.Translate(context.Background(), translationRequest{
+				SourceLang:  "en",
+				TargetLang:  "de",
+				Text:        "a <b> c",
+				TagHandling: tc.tagHandling,
+			}); err != nil {
+				t.Fatalf("translating: %s", err)
+			}
+
+			form := api.lastForm(t)
+			if _, ok := form["tag_handling"]; ok != tc.present || form.Get("tag_handling") != tc.expected {
+				t.Errorf("unexpected tag_handling %q (present: %v)", form.Get("tag_handling"), ok)
+			}
+		})
+	}
+}
+
+func TestDeeplRetriesTransientErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		statuses   []int
+		maxRetries int
+		attempts   int
+		wantErr    bool
+	}{
+		{name: "rate limited", statuses: []int{429, 429}, maxRetries: 3, attempts: 3},
+		{name: "server error", statuses: []int{503}, maxRetries: 3, attempts: 2},
+		{name: "retries exhausted", statuses: []int{429, 429, 429}, maxRetries: 2, attempts: 3, wantErr: true},
+		{name: "retries disabled", statuses: []int{500}, maxRetries: 0, attempts: 1, wantErr: true},
+		{name: "client error", statuses: []int{400}, maxRetries: 3, attempts: 1, wantErr: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				mu       sync.Mutex
+				attempts int
+			)
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if err := r.ParseForm(); err != nil || r.PostForm.Get("text") != "Hello" {
+					t.Errorf("request body was not repeated: %v %q", err, r.PostForm)
+				}
+
+				mu.Lock()
+				attempt := attempts
+				attempts++
+				mu.Unlock()
+
+				if attempt < len(tc.statuses) {
+					w.Header().Set("Retry-After", "0")
+					w.WriteHeader(tc.statuses[attempt])
+					return
+				}
+
+				_ = json.NewEncoder(w).Encode(map[string]any{
+					"translations": []map[string]string{{"text": "Hallo"}},
+				})
+			}))
+			defer srv.Close()
+
+			dt := newDeeplTranslator(srv.Client(), srv.URL, "test", deeplOptions{translatorOptions: translatorOptions{MaxRetries: tc.maxRetries}})
+			text, err := dt.Translate(context.Background(), translationRequest{SourceLang: "en", TargetLang: "de", Text: "Hello", TagHandling: tagHandlingOff})
+
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !tc.wantErr && text != "Hallo" {
+				t.Errorf("unexpected translation %q", text)
+			}
+			if attempts != tc.attempts {
+				t.Errorf("expected %d attempts, got %d", tc.attempts, attempts)
+			}
+		})
+	}
+}
+
+func TestDeeplPreserveFormatting(t *testing.T) {
+	api := newDeeplTestAPI(t)
+
+	for _, enabled := range []bool{false, true} {
+		dt := api.translator(deeplOptions{PreserveFormatting: enabled})
+		if _, err := dt.Translate(context.Background(), translationRequest{SourceLang: "en", TargetLang: "de", Text: " hello "}); err != nil {
+			t.Fatalf("translating: %s", err)
+		}
+
+		form := api.lastForm(t)
+		if _, ok := form["preserve_formatting"]; ok != enabled {
+			t.Errorf("preserve_formatting present = %v with preserve-formatting %v", ok, enabled)
+		}
+		if enabled && form.Get("preserve_formatting") != "1" {
+			t.Errorf("unexpected preserve_formatting %q", form.Get("preserve_formatting"))
+		}
+	}
+}
+
+func TestDeeplFlagsInRequest(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		args     []string
+		expected map[string]string
+	}{
+		{
+			name:     "defaults",
+			expected: map[string]string{"tag_handling": tagHandlingHTML, "split_sentences": "", "preserve_formatting": ""},
+		},
+		{
+			name:     "new flags",
+			args:     []string{"--deepl-preserve-formatting", "--deepl-split-sentences=nonewlines", "--deepl-tag-handling=xml"},
+			expected: map[string]string{"tag_handling": tagHandlingXML, "split_sentences": "nonewlines", "preserve_formatting": "1"},
+		},
+		{
+			name:     "deprecated flags",
+			args:     []string{"--preserve-formatting", "--split-sentences=0", "--tag-handling=off"},
+			expected: map[string]string{"tag_handling": "", "split_sentences": "0", "preserve_formatting": "1"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			api := newDeeplTestAPI(t)
+			testConfig(t, append([]string{"--deepl-api-key=test", "--deepl-api-endpoint=" + api.URL + "/v2/translate"}, tc.args...)...)
+
+			dt, err := getTranslatorByType("deepl")
+			if err != nil {
+				t.Fatalf("creating translator: %s", err)
+			}
+
+			tf := translationFile{}
+			if _, err = dt.Translate(context.Background(), translationRequest{
+				SourceLang:  "EN",
+				TargetLang:  "DE",
+				TagHandling: tf.tagHandling("key"),
+				Text:        "hello",
+			}); err != nil {
+				t.Fatalf("translating: %s", err)
+			}
+
+			form := api.lastForm(t)
+			for param, value := range tc.expected {
+				if got := form.Get(param); got != value {
+					t.Errorf("%s = %q, want %q", param, got, value)
+				}
+			}
+		})
+	}
+}
+
+func TestDeeplSourceLanguageIsBaseCode(t *testing.T) {
+	api := newDeeplTestAPI(t)
+	dt := api.translator(deeplOptions{})
+
+	for code, expected := range map[string]string{
+		"EN":    "EN",
+		"de":    "DE",
+		"PT-BR": "PT",
+		"en-gb": "EN",
+	} {
+		if _, err := dt.Translate(context.Background(), translationRequest{SourceLang: code, TargetLang: "FR", Text: "x"}); err != nil {
+			t.Fatalf("translating: %s", err)
+		}
+
+		if got := api.lastForm(t).Get("source_lang"); got != expected {
+			t.Errorf("source_lang for %s = %q, want %q", code, got, expected)
+		}
+	}
+}
+
+func TestDecodeAddedEntities(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		source   string
+		text     string
+		expected string
+	}{
+		{"ampersand", "Tom & Jerry", "Tom &amp; Jerry", "Tom & Jerry"},
+		{"apostrophe", "It's", "C&#39;est", "C'est"},
+		{"quotes", `Say "hi"`, "Sag &quot;hallo&quot;", `Sag "hallo"`},
+		{"hex reference", "It's", "C&#x27;est", "C'est"},
+		{"entity of the source", "Use &lt;b&gt; &amp; more", "Nutze &lt;b&gt; &amp; mehr", "Nutze &lt;b&gt; &amp; mehr"},
+		{"mixed", "&quot;A&quot; & B", "&quot;A&quot; &amp; B", "&quot;A&quot; & B"},
+		{"decoded once", "&amp;", "&amp;amp;", "&amp;amp;"},
+		{"added double encoding", "a & b", "a &amp;amp; b", "a &amp; b"},
+		{"no entities", "Hello", "Hallo", "Hallo"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := decodeAddedEntities(tc.text, tc.source); got != tc.expected {
+				t.Errorf("decodeAddedEntities(%q, %q) = %q, want %q", tc.text, tc.source, got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestDeeplDecodeEntities(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		decode      bool
+		tagHandling string
+		expected    string
+	}{
+		{"enabled", true, tagHandlingHTML, `Tom & Jerry's "Show"`},
+		{"disabled", false, tagHandlingHTML, "Tom &amp; Jerry&#39;s &quot;Show&quot;"},
+		{"xml tag-handling", true, tagHandlingXML, "Tom &amp; Jerry&#39;s &quot;Show&quot;"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			api := newDeeplTestAPI(t)
+			api.response = "Tom &amp; Jerry&#39;s &quot;Show&quot;"
+
+			text, err := api.translator(deeplOptions{DecodeEntities: tc.decode}).Translate(context.Background(), translationRequest{
+				SourceLang:  "EN",
+				TargetLang:  "DE",
+				TagHandling: tc.tagHandling,
+				Text:        `Tom & Jerry's "show"`,
+			})
+			if err != nil {
+				t.Fatalf("translating: %s", err)
+			}
+
+			if text != tc.expected {
+				t.Errorf("got %q, want %q", text, tc.expected)
+			}
+		})
+	}
+}
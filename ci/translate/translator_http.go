@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// retryBaseDelay is the delay before the first retry, it is doubled
+	// for every further attempt up to retryMaxDelay
+	retryBaseDelay = 500 * time.Millisecond
+	retryMaxDelay  = 30 * time.Second
+)
+
+// doRequest executes the request, mentioning the configured timeout and
+// the flag to change it in the error if the request did not complete in
+// time. Responses with a transient status (429, 5xx) are retried up to
+// MaxRetries times with exponential backoff, honoring the Retry-After
+// header. Every attempt waits for the rate limit. Retries happen within
+// the request timeout of the context.
+func doRequest(client *http.Client, req *http.Request, opts translatorOptions, timeoutFlag string) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		if rateLimiter != nil {
+			if err := rateLimiter.Wait(req.Context()); err != nil {
+				return nil, errors.Wrap(err, "waiting for rate limit")
+			}
+		}
+
+		resp, err := client.Do(req)
+		if errors.Is(err, context.DeadlineExceeded) {
+			return nil, errors.Wrapf(err, "request timed out after %s (%s)", opts.RequestTimeout, timeoutFlag)
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "executing request")
+		}
+
+		if !isTransientStatus(resp.StatusCode) || attempt >= opts.MaxRetries || (req.Body != nil && req.GetBody == nil) {
+			return resp, nil
+		}
+		resp.Body.Close()
+
+		delay := retryDelay(attempt, resp.Header.Get("Retry-After"))
+		logrus.WithFields(logrus.Fields{
+			"attempt": attempt + 1,
+			"delay":   delay,
+			"host":    req.URL.Host,
+			"status":  resp.StatusCode,
+		}).Warn("transient API error, retrying")
+
+		select {
+		case <-req.Context().Done():
+			return nil, errors.Wrapf(req.Context().Err(), "waiting to retry after HTTP status %d", resp.StatusCode)
+		case <-time.After(delay):
+		}
+
+		if req.GetBody != nil {
+			if req.Body, err = req.GetBody(); err != nil {
+				return nil, errors.Wrap(err, "resetting request body")
+			}
+		}
+	}
+}
+
+// isTransientStatus reports whether the status indicates a temporary
+// failure worth retrying: rate limiting and server errors
+func isTransientStatus(status int) bool {
+	return status == http.StatusTooManyRequests || status >= http.StatusInternalServerError
+}
+
+// retryDelay returns the delay before the given retry attempt: the
+// seconds given in the Retry-After header if present and otherwise the
+// exponential backoff, both capped at retryMaxDelay
+func retryDelay(attempt int, retryAfter string) time.Duration {
+	delay := retryMaxDelay
+	if attempt < 16 {
+		delay = retryBaseDelay << attempt
+	}
+	if seconds, err := strconv.Atoi(retryAfter); err == nil && seconds >= 0 {
+		delay = time.Duration(seconds) * time.Second
+	}
+
+	if delay > retryMaxDelay {
+		delay = retryMaxDelay
+	}
+	return delay
+}
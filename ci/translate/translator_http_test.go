@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+func TestDoRequestRateLimitsRetries(t *testing.T) {
+	const interval = 50 * time.Millisecond
+
+	orig := rateLimiter
+	t.Cleanup(func() { rateLimiter = orig })
+	rateLimiter = rate.NewLimiter(rate.Every(interval), 1)
+
+	var arrivals []time.Time
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		arrivals = append(arrivals, time.Now())
+		if len(arrivals) < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	defer srv.Close()
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, srv.URL, nil)
+	if err != nil {
+		t.Fatalf("creating request: %s", err)
+	}
+
+	resp, err := doRequest(srv.Client(), req, translatorOptions{MaxRetries: 3}, "test-timeout")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	resp.Body.Close()
+
+	if len(arrivals) != 3 {
+		t.Fatalf("expected 3 attempts, got %d", len(arrivals))
+	}
+	for i := 1; i < len(arrivals); i++ {
+		// Allow some slack for the token bucket refilling continuously
+		if gap := arrivals[i].Sub(arrivals[i-1]); gap < interval*9/10 {
+			t.Errorf("attempt %d was sent %s after the previous one, expected at least %s", i+1, gap, interval)
+		}
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	for _, tc := range []struct {
+		attempt    int
+		retryAfter string
+		expected   time.Duration
+	}{
+		{0, "", retryBaseDelay},
+		{2, "", 4 * retryBaseDelay},
+		{1, "3", 3 * time.Second},
+		{0, "invalid", retryBaseDelay},
+		{0, "3600", retryMaxDelay},
+		{20, "", retryMaxDelay},
+		{70, "", retryMaxDelay},
+	} {
+		if delay := retryDelay(tc.attempt, tc.retryAfter); delay != tc.expected {
+			t.Errorf("retryDelay(%d, %q) = %s, want %s", tc.attempt, tc.retryAfter, delay, tc.expected)
+		}
+	}
+}
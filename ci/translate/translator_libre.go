@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// libreDefaultRequestTimeout is used if no timeout is configured
+const libreDefaultRequestTimeout = 10 * time.Second
+
+type libreTranslator struct {
+	apiEndpoint string
+	apiKey      string
+	client      *http.Client
+	opts        translatorOptions
+}
+
+func newLibreTranslator(client *http.Client, apiEndpoint, apiKey string, opts translatorOptions) (translator, error) {
+	if apiEndpoint == "" {
+		return nil, errors.New("libre-endpoint not set")
+	}
+
+	if opts.RequestTimeout <= 0 {
+		opts.RequestTimeout = libreDefaultRequestTimeout
+	}
+
+	return &libreTranslator{
+		apiEndpoint: apiEndpoint,
+		apiKey:      apiKey,
+		client:      client,
+		opts:        opts,
+	}, nil
+}
+
+func (libreTranslator) LanguageCode(lang string, tm *translationMapping) string {
+	if tm.LanguageKey != "" {
+		return tm.LanguageKey
+	}
+	return lang
+}
+
+func (l libreTranslator) Translate(ctx context.Context, tr translationRequest) (string, error) {
+	// LibreTranslate only knows about plain text and HTML
+	format := "html"
+	if tr.TagHandling == tagHandlingOff {
+		format = "text"
+	}
+
+	body, err := json.Marshal(struct {
+		Q      string `json:"q"`
+		Source string `json:"source"`
+		Target string `json:"target"`
+		Format string `json:"format"`
+		APIKey string `json:"api_key,omitempty"`
+	}{
+		Q:      tr.Text,
+		Source: tr.SourceLang,
+		Target: tr.TargetLang,
+		Format: format,
+		APIKey: l.apiKey,
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "encoding request")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, l.opts.RequestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, l.apiEndpoint, bytes.NewReader(body))
+	if err != nil {
+		return "", errors.Wrap(err, "creating request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := doRequest(l.client, req, l.opts, "libre-request-timeout")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.Errorf("unexpected HTTP status %d", resp.StatusCode)
+	}
+
+	var payload struct {
+		TranslatedText string `json:"translatedText"`
+		Error          string `json:"error"`
+	}
+
+	if err = json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return "", errors.Wrap(err, "decoding LibreTranslate response")
+	}
+
+	if payload.Error != "" {
+		return "", errors.Errorf("LibreTranslate error: %s", payload.Error)
+	}
+
+	return payload.TranslatedText, nil
+}
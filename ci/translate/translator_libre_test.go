@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLibreTranslate(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		status   int
+		body     string
+		expected string
+		wantErr  string
+	}{
+		{"success", http.StatusOK, `{"translatedText":"Hallo"}`, "Hallo", ""},
+		{"api error", http.StatusOK, `{"error":"unsupported language"}`, "", "LibreTranslate error: unsupported language"},
+		{"bad request", http.StatusBadRequest, `{"error":"invalid request"}`, "", "unexpected HTTP status 400"},
+		{"server error", http.StatusBadGateway, `<html>Bad Gateway</html>`, "", "unexpected HTTP status 502"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.status)
+				_, _ = w.Write([]byte(tc.body))
+			}))
+			defer srv.Close()
+
+			lt, err := newLibreTranslator(srv.Client(), srv.URL, "", translatorOptions{})
+			if err != nil {
+				t.Fatalf("creating translator: %s", err)
+			}
+
+			text, err := lt.Translate(context.Background(), translationRequest{SourceLang: "en", TargetLang: "de", Text: "Hello"})
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("expected error %q, got %v", tc.wantErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if text != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, text)
+			}
+		})
+	}
+}
+
+func TestLibreRequestTimeout(t *testing.T) {
+	done := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-done:
+		}
+	}))
+	defer srv.Close()
+	defer close(done)
+
+	lt, err := newLibreTranslator(srv.Client(), srv.URL, "", translatorOptions{RequestTimeout: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("creating translator: %s", err)
+	}
+
+	_, err = lt.Translate(context.Background(), translationRequest{SourceLang: "en", TargetLang: "de", Text: "Hello"})
+	if err == nil || !strings.Contains(err.Error(), "timed out after 50ms (libre-request-timeout)") {
+		t.Fatalf("expected timeout error, got %v", err)
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// llmDefaultRequestTimeout is used if no timeout is configured
+	llmDefaultRequestTimeout = 60 * time.Second
+	llmSystemPrompt          = `You are a professional translator for the user interface of a web application.
+Translate the text given by the user from the language %q into the language %q.
+Keep all HTML tags, HTML entities and placeholders (like {name} or %%s) exactly as they are.
+Respond only with the translated text: no explanations, no notes, no quotes.`
+)
+
+type llmTranslator struct {
+	apiEndpoint string
+	apiKey      string
+	model       string
+	client      *http.Client
+	opts        translatorOptions
+}
+
+func newLLMTranslator(client *http.Client, apiEndpoint, model, apiKey string, opts translatorOptions) (translator, error) {
+	if apiEndpoint == "" {
+		return nil, errors.New("llm-endpoint not set")
+	}
+
+	if model == "" {
+		return nil, errors.New("llm-model not set")
+	}
+
+	if opts.RequestTimeout <= 0 {
+		opts.RequestTimeout = llmDefaultRequestTimeout
+	}
+
+	return &llmTranslator{
+		apiEndpoint: apiEndpoint,
+		apiKey:      apiKey,
+		model:       model,
+		client:      client,
+		opts:        opts,
+	}, nil
+}
+
+func (llmTranslator) LanguageCode(lang string, tm *translationMapping) string {
+	if tm.LanguageKey != "" {
+		return tm.LanguageKey
+	}
+	return lang
+}
+
+func (l llmTranslator) Translate(ctx context.Context, tr translationRequest) (string, error) {
+	type message struct {
+		Role    string `json:"role"`
+		Content string `json:"content"`
+	}
+
+	prompt := fmt.Sprintf(llmSystemPrompt, tr.SourceLang, tr.TargetLang)
+	if tr.Description != "" {
+		prompt = strings.Join([]string{prompt, "Context of the text (do not translate): " + tr.Description}, "\n")
+	}
+
+	body, err := json.Marshal(struct {
+		Model       string    `json:"model"`
+		Messages    []message `json:"messages"`
+		Temperature float64   `json:"temperature"`
+	}{
+		Model: l.model,
+		Messages: []message{
+			{Role: "system", Content: prompt},
+			{Role: "user", Content: tr.Text},
+		},
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "encoding request")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, l.opts.RequestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, l.apiEndpoint, bytes.NewReader(body))
+	if err != nil {
+		return "", errors.Wrap(err, "creating request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if l.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+l.apiKey)
+	}
+
+	resp, err := doRequest(l.client, req, l.opts, "llm-request-timeout")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.Errorf("unexpected HTTP status %d", resp.StatusCode)
+	}
+
+	var payload struct {
+		Choices []struct {
+			Message message `json:"message"`
+		} `json:"choices"`
+		Error *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+
+	if err = json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return "", errors.Wrap(err, "decoding LLM response")
+	}
+
+	if payload.Error != nil {
+		return "", errors.Errorf("LLM error: %s", payload.Error.Message)
+	}
+
+	if l := len(payload.Choices); l != 1 {
+		return "", errors.Errorf("unexpected number of choices: %d", l)
+	}
+
+	return cleanLLMResponse(tr.Text, payload.Choices[0].Message.Content), nil
+}
+
+// cleanLLMResponse strips decorations models tend to add around the
+// translated text: surrounding whitespace, code fences and quotes not
+// present in the source text
+func cleanLLMResponse(src, resp string) string {
+	resp = strings.TrimSpace(resp)
+
+	if strings.HasPrefix(resp, "```") && strings.HasSuffix(resp, "```") {
+		resp = strings.TrimPrefix(resp, "```")
+		resp = strings.TrimSuffix(resp, "```")
+		if nl := strings.IndexByte(resp, '\n'); nl >= 0 && !strings.Contains(resp[:nl], " ") {
+			// Drop language hint of the code fence
+			resp = resp[nl+1:]
+		}
+		resp = strings.TrimSpace(resp)
+	}
+
+	for _, quotes := range [][2]string{{`"`, `"`}, {`'`, `'`}, {"“", "”"}, {"„", "“"}} {
+		if len(resp) > len(quotes[0])+len(quotes[1]) &&
+			strings.HasPrefix(resp, quotes[0]) && strings.HasSuffix(resp, quotes[1]) &&
+			!strings.HasPrefix(src, quotes[0]) {
+			resp = strings.TrimSuffix(strings.TrimPrefix(resp, quotes[0]), quotes[1])
+		}
+	}
+
+	return resp
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestLLMTranslateStatus(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		statuses   []int
+		maxRetries int
+		attempts   int
+		wantErr    string
+	}{
+		{"success", nil, 3, 1, ""},
+		{"retried rate limit", []int{http.StatusTooManyRequests, http.StatusServiceUnavailable}, 3, 3, ""},
+		{"retries exhausted", []int{http.StatusTooManyRequests, http.StatusTooManyRequests}, 1, 2, "unexpected HTTP status 429"},
+		{"server error without retries", []int{http.StatusInternalServerError}, 0, 1, "unexpected HTTP status 500"},
+		{"client error", []int{http.StatusUnauthorized}, 3, 1, "unexpected HTTP status 401"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				mu       sync.Mutex
+				attempts int
+			)
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if body, err := io.ReadAll(r.Body); err != nil || !strings.Contains(string(body), `"content":"Hello"`) {
+					t.Errorf("request body was not repeated: %v %q", err, body)
+				}
+
+				mu.Lock()
+				attempt := attempts
+				attempts++
+				mu.Unlock()
+
+				if attempt < len(tc.statuses) {
+					w.Header().Set("Retry-After", "0")
+					w.WriteHeader(tc.statuses[attempt])
+					_, _ = w.Write([]byte("<html>error</html>"))
+					return
+				}
+
+				_ = json.NewEncoder(w).Encode(map[string]any{
+					"choices": []map[string]any{{"message": map[string]string{"role": "assistant", "content": "Hallo"}}},
+				})
+			}))
+			defer srv.Close()
+
+			lt, err := newLLMTranslator(srv.Client(), srv.URL, "test", "", translatorOptions{MaxRetries: tc.maxRetries})
+			if err != nil {
+				t.Fatalf("creating translator: %s", err)
+			}
+
+			text, err := lt.Translate(context.Background(), translationRequest{SourceLang: "en", TargetLang: "de", Text: "Hello"})
+			switch {
+			case tc.wantErr != "":
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Errorf("expected error %q, got %v", tc.wantErr, err)
+				}
+			case err != nil:
+				t.Errorf("unexpected error: %s", err)
+			case text != "Hallo":
+				t.Errorf("unexpected translation %q", text)
+			}
+
+			if attempts != tc.attempts {
+				t.Errorf("expected %d attempts, got %d", tc.attempts, attempts)
+			}
+		})
+	}
+}
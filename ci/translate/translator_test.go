@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+// fakeTranslator records all requests and returns the text prefixed
+// with the target language code
+type fakeTranslator struct {
+	// fail contains texts to return an error for
+	fail map[string]bool
+
+	mu       sync.Mutex
+	requests []translationRequest
+}
+
+func (*fakeTranslator) LanguageCode(lang string, tm *translationMapping) string {
+	if tm.DeeplLanguage != "" {
+		return tm.DeeplLanguage
+	}
+	return strings.ToUpper(lang)
+}
+
+func (f *fakeTranslator) Translate(_ context.Context, req translationRequest) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.requests = append(f.requests, req)
+	if f.fail[req.Text] {
+		return "", errors.New("translation failed")
+	}
+	return req.TargetLang + ":" + req.Text, nil
+}
+
+// texts returns the target language and text of all recorded requests
+func (f *fakeTranslator) texts() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var out []string
+	for _, req := range f.requests {
+		out = append(out, req.SourceLang+">"+req.TargetLang+" "+req.Text)
+	}
+	return out
+}
+
+func TestAutoTranslateCallSequence(t *testing.T) {
+	testConfig(t, "--deepl-api-key=test", "--concurrency=1")
+
+	ft := &fakeTranslator{}
+	tf := loadTestFile(t, testTranslationYAML)
+
+	if err := autoTranslate(context.Background(), ft, &tf); err != nil {
+		t.Fatalf("translating: %s", err)
+	}
+
+	expected := []string{
+		// de already contains the greeting
+		"EN>DE Home",
+		"EN>DE About",
+		"EN>DE Text",
+		"EN>DE Title",
+		"EN>FR Hello {name}",
+		"EN>FR Home",
+		"EN>FR About",
+		"EN>FR Text",
+		"EN>FR Title",
+	}
+	if calls := ft.texts(); !reflect.DeepEqual(calls, expected) {
+		t.Errorf("unexpected calls:\n got: %q\nwant: %q", calls, expected)
+	}
+
+	if got := tf.Translations["fr"].Translations["menu"]; !reflect.DeepEqual(got, []any{"FR:Home", "FR:About"}) {
+		t.Errorf("unexpected fr menu: %#v", got)
+	}
+}
+
+func TestGetTranslatorByType(t *testing.T) {
+	testConfig(t, "--libre-endpoint=http://localhost")
+
+	for _, tc := range []struct {
+		name    string
+		wantErr bool
+	}{
+		{"deepl", false},
+		{"libre", false},
+		{"unknown", true},
+	} {
+		if _, err := getTranslatorByType(tc.name); (err != nil) != tc.wantErr {
+			t.Errorf("getTranslatorByType(%q): unexpected error %v", tc.name, err)
+		}
+	}
+}
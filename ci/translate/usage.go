@@ -0,0 +1,196 @@
+package main
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// usageRegex matches a translation call in the source files, its first
+// group contains the key
+var usageRegex *regexp.Regexp
+
+// scanUsage searches the files below the directory for translation
+// calls and returns the keys referenced by them. Hidden directories and
+// node_modules are skipped.
+func scanUsage(dir string) (map[string]bool, error) {
+	used := make(map[string]bool)
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			if path != dir && (strings.HasPrefix(d.Name(), ".") || d.Name() == "node_modules") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return errors.Wrapf(err, "reading %s", path)
+		}
+
+		for _, match := range usageRegex.FindAllSubmatch(content, -1) {
+			used[string(match[1])] = true
+		}
+
+		return nil
+	})
+
+	return used, errors.Wrap(err, "walking directory")
+}
+
+// unusedKeys returns the reference keys not referenced by any of the
+// used keys: a key is used if it or one of the paths below it is used.
+// Keys matching the dynamic-keys patterns are always considered used.
+func unusedKeys(tf translationFile, used map[string]bool) (unused []string) {
+	for _, key := range tf.ReferenceKeys() {
+		if used[key] || isDynamicKey(key) {
+			continue
+		}
+
+		var found bool
+		for u := range used {
+			if strings.HasPrefix(u, key+".") {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			unused = append(unused, key)
+		}
+	}
+
+	return unused
+}
+
+// isDynamicKey checks the key against the glob patterns of keys which
+// are referenced dynamically in the source files
+func isDynamicKey(key string) bool {
+	for _, pattern := range cfg.DynamicKeys {
+		if match, _ := path.Match(pattern, key); match {
+			return true
+		}
+	}
+	return false
+}
+
+// reportUnusedKeys scans the directory for the reference keys, logs the
+// unused ones and returns them
+func reportUnusedKeys(tf translationFile, dir string) ([]string, error) {
+	used, err := scanUsage(dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "scanning usage")
+	}
+
+	unused := unusedKeys(tf, used)
+	for _, key := range unused {
+		logrus.WithField("key", key).Warn("key is not used")
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"keys":   len(tf.Reference.Translations),
+		"unused": len(unused),
+	}).Info("scanned key usage")
+
+	return unused, nil
+}
+
+// removeKeys deletes the keys from the reference and all languages
+// including their fingerprints and provenance
+func removeKeys(tf *translationFile, keys []string) {
+	for _, key := range keys {
+		delete(tf.Reference.Translations, key)
+		delete(tf.Reference.Descriptions, key)
+		delete(tf.Reference.TagHandling, key)
+
+		for _, tm := range tf.Translations {
+			delete(tm.Translations, key)
+			tm.deleteMeta(key)
+		}
+	}
+
+	logrus.WithField("count", len(keys)).Info("removed unused keys")
+}
+
+// missingReferenceKeys returns the sorted keys referenced in the source
+// files which are neither a reference key nor a path below one
+func missingReferenceKeys(tf translationFile, used map[string]bool) (missing []string) {
+	for u := range used {
+		if _, ok := tf.Reference.Translations[u]; ok {
+			continue
+		}
+
+		var found bool
+		for key := range tf.Reference.Translations {
+			if strings.HasPrefix(u, key+".") {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			missing = append(missing, u)
+		}
+	}
+
+	sort.Strings(missing)
+	return missing
+}
+
+// extractKeys scans the directory for translation calls and reports
+// the keys missing in the reference and the reference keys not used.
+// With add-missing the missing keys are added to the reference with
+// empty strings.
+func extractKeys(tf *translationFile, dir string) error {
+	used, err := scanUsage(dir)
+	if err != nil {
+		return errors.Wrap(err, "scanning usage")
+	}
+
+	missing := missingReferenceKeys(*tf, used)
+	for _, key := range missing {
+		logrus.WithField("key", key).Warn("key is missing in reference")
+	}
+
+	unused := unusedKeys(*tf, used)
+	for _, key := range unused {
+		logrus.WithField("key", key).Warn("key is not used")
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"used":    len(used),
+		"missing": len(missing),
+		"unused":  len(unused),
+	}).Info("extracted keys")
+
+	if !cfg.AddMissing || len(missing) == 0 {
+		return nil
+	}
+
+	if tf.Reference.Translations == nil {
+		tf.Reference.Translations = make(translation)
+	}
+
+	for _, key := range missing {
+		tf.Reference.Translations[key] = ""
+	}
+
+	logrus.WithField("count", len(missing)).Info("added missing keys to reference")
+	return nil
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// validateTranslationFile checks the structure of the translation file
+// and returns all problems found
+func validateTranslationFile(tf translationFile) (problems []string) {
+	if tf.Reference.LanguageKey == "" {
+		problems = append(problems, "reference: missing languageKey")
+	}
+
+	usesDeepl := cfg.Translator == "deepl"
+	for _, tm := range tf.Translations {
+		if tm != nil && tm.Provider == "deepl" {
+			usesDeepl = true
+		}
+	}
+
+	if tf.Reference.DeeplLanguage == "" && usesDeepl {
+		problems = append(problems, "reference: missing deeplLanguage")
+	}
+
+	problems = append(problems, validateLocale("reference", &tf.Reference)...)
+
+	for _, ref := range tf.FallbackReferences {
+		if tf.Translations[ref] == nil {
+			problems = append(problems, fmt.Sprintf("fallbackReferences: unknown language %q", ref))
+		}
+	}
+
+	if fb := cfg.FallbackLanguage; fb != "" && fb != tf.Reference.LanguageKey && tf.Translations[fb] == nil {
+		problems = append(problems, fmt.Sprintf("fallback-language: unknown language %q", fb))
+	}
+
+	for _, lang := range cfg.Languages {
+		if tf.Translations[lang] == nil {
+			problems = append(problems, fmt.Sprintf("languages: unknown language %q (valid: %s)", lang, strings.Join(tf.Languages(), ", ")))
+		}
+	}
+
+	// The map key acts as language key if none is given explicitly
+	seen := map[string]string{tf.Reference.LanguageKey: "reference"}
+	for _, lang := range tf.Languages() {
+		tm := tf.Translations[lang]
+		if tm == nil {
+			problems = append(problems, fmt.Sprintf("translations.%s: empty language definition", lang))
+			continue
+		}
+
+		langKey := lang
+		if tm.LanguageKey != "" {
+			langKey = tm.LanguageKey
+		}
+
+		if other, ok := seen[langKey]; ok {
+			problems = append(problems, fmt.Sprintf("translations.%s: languageKey %q already used by %s", lang, langKey, other))
+		}
+		seen[langKey] = "translations." + lang
+
+		problems = append(problems, validateLocale("translations."+lang, tm)...)
+
+		switch tm.Provider {
+		case "", "deepl", "libre", "llm":
+		default:
+			problems = append(problems, fmt.Sprintf("translations.%s: unknown provider %q", lang, tm.Provider))
+		}
+
+		for _, key := range tf.ReferenceKeys() {
+			problems = append(problems, compareValueTypes(
+				strings.Join([]string{"translations", lang, key}, "."),
+				tf.Reference.Translations[key],
+				tm.Translations[key],
+			)...)
+		}
+	}
+
+	return problems
+}
+
+// validateLocale checks the locale fields as they are rendered into the
+// output without escaping
+func validateLocale(path string, tm *translationMapping) (problems []string) {
+	if tm.BCP47 != "" && !bcp47Regex.MatchString(tm.BCP47) {
+		problems = append(problems, fmt.Sprintf("%s: invalid bcp47 tag %q", path, tm.BCP47))
+	}
+
+	switch tm.Dir {
+	case "", textDirLTR, textDirRTL:
+	default:
+		problems = append(problems, fmt.Sprintf("%s: dir must be %s or %s", path, textDirLTR, textDirRTL))
+	}
+
+	return problems
+}
+
+// compareValueTypes checks the translated value has the same structure
+// as the reference value. Missing values are not reported as they are
+// filled by the translation.
+func compareValueTypes(path string, ref, value any) (problems []string) {
+	ref, value = normalizeValue(ref), normalizeValue(value)
+	if value == nil {
+		return nil
+	}
+
+	if valueKind(ref) != valueKind(value) {
+		return []string{fmt.Sprintf("%s: expected %s, found %s", path, valueKind(ref), valueKind(value))}
+	}
+
+	switch r := ref.(type) {
+	case []any:
+		v := value.([]any)
+		for i := 0; i < len(r) && i < len(v); i++ {
+			problems = append(problems, compareValueTypes(path+"."+strconv.Itoa(i), r[i], v[i])...)
+		}
+
+	case map[string]any:
+		v := value.(map[string]any)
+		for _, key := range sortedMapKeys(r) {
+			problems = append(problems, compareValueTypes(path+"."+key, r[key], v[key])...)
+		}
+	}
+
+	return problems
+}
+
+func valueKind(value any) string {
+	switch value.(type) {
+	case string:
+		return "string"
+	case []any:
+		return "list"
+	case map[string]any:
+		return "map"
+	case int, float64:
+		return "number"
+	case bool:
+		return "bool"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// duplicateKeys walks the YAML node tree and reports every mapping key
+// defined more than once including the lines of both definitions
+func duplicateKeys(node *yaml.Node, path []string) (dups []string) {
+	switch node.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for i, child := range node.Content {
+			childPath := path
+			if node.Kind == yaml.SequenceNode {
+				childPath = append(append([]string{}, path...), strconv.Itoa(i))
+			}
+			dups = append(dups, duplicateKeys(child, childPath)...)
+		}
+
+	case yaml.MappingNode:
+		seen := make(map[string]int)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i]
+			keyPath := append(append([]string{}, path...), key.Value)
+
+			if line, ok := seen[key.Value]; ok {
+				dups = append(dups, fmt.Sprintf(
+					"duplicate key %q at line %d (first defined at line %d)",
+					strings.Join(keyPath, "."), key.Line, line,
+				))
+			} else {
+				seen[key.Value] = key.Line
+			}
+
+			dups = append(dups, duplicateKeys(node.Content[i+1], keyPath)...)
+		}
+	}
+
+	return dups
+}
+
+// emptyReferenceStrings returns the sorted paths of the reference
+// strings (including list elements) containing only whitespace
+func emptyReferenceStrings(tf translationFile) (paths []string) {
+	for _, key := range tf.ReferenceKeys() {
+		for path, str := range leafStrings(key, tf.Reference.Translations[key]) {
+			if strings.TrimSpace(str) == "" {
+				paths = append(paths, path)
+			}
+		}
+	}
+
+	sort.Strings(paths)
+	return paths
+}
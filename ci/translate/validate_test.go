@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+const testEmptyReferenceYAML = `reference:
+  deeplLanguage: EN
+  translations:
+    blank: "  "
+    empty: ""
+    list: [One, ""]
+    nested:
+      empty: ""
+      text: Text
+    text: Text
+translations:
+  de:
+    deeplLanguage: DE
+    translations: {}
+`
+
+func TestEmptyReferenceStrings(t *testing.T) {
+	testConfig(t)
+
+	tf := loadTestFile(t, testEmptyReferenceYAML)
+
+	expected := []string{"blank", "empty", "list.1", "nested.empty"}
+	if got := emptyReferenceStrings(tf); !reflect.DeepEqual(got, expected) {
+		t.Errorf("got %q, want %q", got, expected)
+	}
+}
+
+func TestStrictEmptyReferenceStrings(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		yaml string
+		args []string
+		fail bool
+	}{
+		{name: "warn only", yaml: testEmptyReferenceYAML},
+		{name: "strict", yaml: testEmptyReferenceYAML, args: []string{"--strict"}, fail: true},
+		{name: "strict without empty strings", yaml: testTranslationYAML, args: []string{"--strict"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			testConfig(t, append([]string{"--deepl-api-key=test", "--concurrency=1"}, tc.args...)...)
+
+			ft := &fakeTranslator{}
+			tf := loadTestFile(t, tc.yaml)
+
+			err := autoTranslate(context.Background(), ft, &tf)
+			if (err != nil) != tc.fail {
+				t.Fatalf("autoTranslate() = %v, want failure %v", err, tc.fail)
+			}
+
+			if tc.fail && len(ft.requests) > 0 {
+				t.Errorf("translated despite failing: %q", ft.texts())
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"math/rand"
+	"sort"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/sirupsen/logrus"
+)
+
+// verificationSample collects the translations of randomly picked tasks
+// to translate them back into the reference language after the run
+type verificationSample struct {
+	mu      sync.Mutex
+	results []verificationResult
+}
+
+type verificationResult struct {
+	task  translationTask
+	value string
+}
+
+// Pick selects up to n of the given tasks and hooks into their apply
+// functions to record the translation stored for them
+func (v *verificationSample) Pick(tasks []translationTask, n int) {
+	for _, i := range rand.Perm(len(tasks)) {
+		if n <= 0 {
+			return
+		}
+		n--
+
+		task, apply := tasks[i], tasks[i].apply
+		tasks[i].apply = func(value string) {
+			apply(value)
+
+			v.mu.Lock()
+			defer v.mu.Unlock()
+			v.results = append(v.results, verificationResult{task, value})
+		}
+	}
+}
+
+// Log translates the recorded translations back into the reference
+// language and logs them next to the original string. The requests are
+// accounted like translations and stop once the quota of the provider
+// is exhausted. Failures are only logged as the verification does not
+// influence the translation file.
+func (v *verificationSample) Log(ctx context.Context, tf *translationFile, providers *providerSet) {
+	sort.Slice(v.results, func(i, j int) bool {
+		a, b := v.results[i].task, v.results[j].task
+		return a.Lang < b.Lang || (a.Lang == b.Lang && a.Path < b.Path)
+	})
+
+	for _, r := range v.results {
+		task, value := r.task, r.value
+		t := task.translator
+
+		req := translationRequest{
+			SourceLang:  t.LanguageCode(task.Lang, tf.Translations[task.Lang]),
+			TargetLang:  t.LanguageCode(tf.Reference.LanguageKey, &tf.Reference),
+			Text:        value,
+			TagHandling: tf.tagHandling(task.Path),
+		}
+
+		logger := logrus.WithFields(logrus.Fields{
+			"lang":        task.Lang,
+			"key":         task.Path,
+			"original":    task.Source,
+			"translation": value,
+		})
+
+		quota, err := providers.quota(ctx, tf.Translations[task.Lang].provider())
+		if err != nil {
+			logger.WithError(err).Warn("checking quota for back-translation")
+			return
+		}
+
+		if err = quota.Reserve(int64(utf8.RuneCountInString(value))); err != nil {
+			logger.WithError(err).Warn("skipping remaining back-translations")
+			return
+		}
+
+		back, err := fetchTranslation(ctx, task.Lang, t, req)
+		if err != nil {
+			logger.WithError(err).Warn("fetching back-translation")
+			continue
+		}
+
+		logger.WithField("back_translation", back).Info("back-translation")
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+// quotaTranslator is a fakeTranslator reporting a fixed usage
+type quotaTranslator struct {
+	fakeTranslator
+	used, limit int64
+}
+
+func (q *quotaTranslator) Usage(context.Context) (used, limit int64, err error) {
+	return q.used, q.limit, nil
+}
+
+func TestVerificationSample(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		limit     int64
+		requested []string
+		apiCalls  int
+	}{
+		{
+			name:      "unlimited",
+			requested: []string{"EN>DE Hello", "DE>EN DE:Hello"},
+			apiCalls:  2,
+		},
+		{
+			// Enough for the translation (5) but not the back-translation (8)
+			name:      "quota exhausted",
+			limit:     10,
+			requested: []string{"EN>DE Hello"},
+			apiCalls:  1,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			testConfig(t, "--deepl-api-key=test", "--concurrency=1", "--verify-sample=1")
+
+			qt := &quotaTranslator{limit: tc.limit}
+			tf := loadTestFile(t, "reference:\n  deeplLanguage: EN\n  translations:\n    greeting: Hello\ntranslations:\n  de:\n    deeplLanguage: DE\n    translations: {}\n")
+
+			if err := autoTranslate(context.Background(), qt, &tf); err != nil {
+				t.Fatalf("translating: %s", err)
+			}
+
+			if calls := qt.texts(); !reflect.DeepEqual(calls, tc.requested) {
+				t.Errorf("unexpected calls:\n got: %q\nwant: %q", calls, tc.requested)
+			}
+
+			if got := tf.Translations["de"].Translations["greeting"]; got != "DE:Hello" {
+				t.Errorf("back-translation changed the translation: %q", got)
+			}
+
+			ls := summary.Report().Languages["de"]
+			if ls.APICalls != tc.apiCalls {
+				t.Errorf("api calls = %d, want %d", ls.APICalls, tc.apiCalls)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// watchPollInterval defines how often the translation file is
+	// checked for changes
+	watchPollInterval = 500 * time.Millisecond
+	// watchDebounce defines how long the file must stay unchanged after
+	// a change before running again, so a burst of saves triggers one run
+	watchDebounce = time.Second
+)
+
+// watchTranslationFile runs the translation once and again every time
+// the translation file changes until the process is interrupted. Errors
+// are logged and do not stop the watcher.
+func watchTranslationFile() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	run := func() {
+		logrus.Info("loading translations...")
+
+		tf, err := loadTranslationFile()
+		if err != nil {
+			logrus.WithError(err).Error("loading translation file")
+			return
+		}
+
+		if problems := validateTranslationFile(tf); len(problems) > 0 {
+			for _, problem := range problems {
+				logrus.Error(problem)
+			}
+			logrus.WithField("count", len(problems)).Error("translation file is invalid")
+			return
+		}
+
+		if err = runTranslation(tf); err != nil {
+			logrus.WithError(err).Error("translating")
+		}
+	}
+
+	run()
+	// The run itself might have changed the file, start watching from here
+	lastHash, _ := fileHash(cfg.TranslationFile)
+
+	var (
+		changedAt time.Time
+		ticker    = time.NewTicker(watchPollInterval)
+	)
+	defer ticker.Stop()
+
+	logrus.WithField("file", cfg.TranslationFile).Info("watching for changes...")
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-ticker.C:
+			hash, err := fileHash(cfg.TranslationFile)
+			if err != nil {
+				logrus.WithError(err).Error("checking translation file")
+				continue
+			}
+
+			if hash != lastHash {
+				lastHash = hash
+				changedAt = time.Now()
+				continue
+			}
+
+			if changedAt.IsZero() || time.Since(changedAt) < watchDebounce {
+				continue
+			}
+
+			changedAt = time.Time{}
+			logrus.Info("translation file changed")
+			run()
+
+			if lastHash, err = fileHash(cfg.TranslationFile); err != nil {
+				logrus.WithError(err).Error("checking translation file")
+			}
+		}
+	}
+}
+
+func fileHash(filename string) ([sha256.Size]byte, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return [sha256.Size]byte{}, errors.Wrap(err, "reading file")
+	}
+	return sha256.Sum256(data), nil
+}
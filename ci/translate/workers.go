@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// progressStep defines after how many percent of the tasks the progress
+// is logged at info level
+const progressStep = 10
+
+// runTranslationTasks fetches the translations for all tasks using the
+// given number of workers and stores them into the translation file.
+// The checkpoint is called after every saveInterval stored translations
+// or, if saveInterval is zero, after all tasks of a language are done.
+// The first error cancels all remaining tasks unless continueOnError is
+// set: failed translations are then collected and returned as
+// translationFailures after all other tasks are done.
+func runTranslationTasks(
+	ctx context.Context,
+	tf *translationFile,
+	tasks []translationTask,
+	concurrency, saveInterval int,
+	continueOnError bool,
+	checkpoint func() error,
+) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		errOnce  sync.Once
+		failures translationFailures
+		firstErr error
+		queue    = make(chan translationTask)
+		storeMu  sync.Mutex
+		wg       sync.WaitGroup
+
+		remaining = make(map[string]int)
+		stored    int
+	)
+
+	if len(tasks) > 0 {
+		logrus.WithField("total", len(tasks)).Info("translating strings...")
+	}
+
+	fail := func(err error) {
+		errOnce.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+
+	for _, task := range tasks {
+		remaining[task.Lang]++
+	}
+
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for task := range queue {
+				value, err := translateTask(ctx, tf, task)
+				if err != nil {
+					if ctx.Err() == nil {
+						summary.update(task.Lang, func(ls *languageSummary) { ls.Failed++ })
+					}
+
+					if continueOnError && ctx.Err() == nil {
+						logrus.WithError(err).WithFields(logrus.Fields{
+							"lang": task.Lang,
+							"key":  task.Path,
+						}).Warn("translation failed, continuing")
+
+						storeMu.Lock()
+						failures = append(failures, translationFailure{task.Lang, task.Path, err})
+						storeMu.Unlock()
+						continue
+					}
+
+					fail(errors.Wrapf(err, "translating %s:%s", task.Lang, task.Path))
+					continue
+				}
+
+				storeMu.Lock()
+				storeTranslation(tf, task, value)
+
+				remaining[task.Lang]--
+				stored++
+				logProgress(stored, len(tasks))
+
+				if (saveInterval > 0 && stored%saveInterval == 0) || (saveInterval == 0 && remaining[task.Lang] == 0) {
+					if err = checkpoint(); err != nil {
+						fail(errors.Wrap(err, "saving progress"))
+					}
+				}
+				storeMu.Unlock()
+			}
+		}()
+	}
+
+enqueue:
+	for _, task := range tasks {
+		select {
+		case queue <- task:
+		case <-ctx.Done():
+			break enqueue
+		}
+	}
+
+	close(queue)
+	wg.Wait()
+
+	if firstErr == nil && len(failures) > 0 {
+		sort.Slice(failures, func(i, j int) bool {
+			a, b := failures[i], failures[j]
+			return a.Lang < b.Lang || (a.Lang == b.Lang && a.Path < b.Path)
+		})
+		return failures
+	}
+
+	return firstErr
+}
+
+type (
+	// translationFailure records a failed translation when continuing
+	// on errors
+	translationFailure struct {
+		Lang string
+		Path string
+		Err  error
+	}
+
+	// translationFailures is returned when translations failed while
+	// continuing on errors
+	translationFailures []translationFailure
+)
+
+func (t translationFailures) Error() string {
+	return fmt.Sprintf("%d translations failed", len(t))
+}
+
+// Log logs every failure as summary at the end of the run
+func (t translationFailures) Log() {
+	for _, f := range t {
+		logrus.WithError(f.Err).WithFields(logrus.Fields{
+			"lang": f.Lang,
+			"key":  f.Path,
+		}).Error("translation failed")
+	}
+}
+
+// logProgress logs every stored translation at debug level and a
+// summary for every progressStep percent at info level
+func logProgress(done, total int) {
+	var (
+		percent = done * 100 / total
+		msg     = fmt.Sprintf("progress: %d/%d (%d%%)", done, total, percent)
+	)
+
+	if logrus.IsLevelEnabled(logrus.DebugLevel) {
+		logrus.Debug(msg)
+		return
+	}
+
+	if done == total || percent/progressStep > (done-1)*100/total/progressStep {
+		logrus.Info(msg)
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+// cancelingTranslator cancels the run when asked for the given text,
+// simulating an interrupt while the request is in flight
+type cancelingTranslator struct {
+	fakeTranslator
+
+	cancel context.CancelFunc
+	text   string
+}
+
+func (c *cancelingTranslator) Translate(ctx context.Context, req translationRequest) (string, error) {
+	if req.Text == c.text {
+		c.cancel()
+		<-ctx.Done()
+		return "", ctx.Err()
+	}
+	return c.fakeTranslator.Translate(ctx, req)
+}
+
+func TestCancellationSavesProgress(t *testing.T) {
+	testConfig(t, "--deepl-api-key=test", "--concurrency=1", "--save-interval=1")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ct := &cancelingTranslator{cancel: cancel, text: "About"}
+	tf := loadTestFile(t, testTranslationYAML)
+
+	err := autoTranslate(ctx, ct, &tf)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected cancellation error, got %v", err)
+	}
+
+	if calls := ct.texts(); len(calls) != 1 || calls[0] != "EN>DE Home" {
+		t.Errorf("expected no requests after cancellation, got %q", calls)
+	}
+
+	saved := readTestFile(t, cfg.TranslationFile)
+	if !strings.Contains(saved, "DE:Home") {
+		t.Errorf("translation stored before cancellation was not saved:\n%s", saved)
+	}
+}
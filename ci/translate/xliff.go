@@ -0,0 +1,221 @@
+package main
+
+import (
+	"encoding/xml"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	xliffNamespace = "urn:oasis:names:tc:xliff:document:1.2"
+	xliffVersion   = "1.2"
+
+	xliffStateFinal       = "final"
+	xliffStateNeedsReview = "needs-review-translation"
+	xliffStateNew         = "new"
+	xliffStateSignedOff   = "signed-off"
+	xliffStateTranslated  = "translated"
+)
+
+type (
+	xliffDocument struct {
+		XMLName xml.Name    `xml:"urn:oasis:names:tc:xliff:document:1.2 xliff"`
+		Version string      `xml:"version,attr"`
+		Files   []xliffFile `xml:"file"`
+	}
+
+	xliffFile struct {
+		Original       string      `xml:"original,attr"`
+		SourceLanguage string      `xml:"source-language,attr"`
+		TargetLanguage string      `xml:"target-language,attr"`
+		Datatype       string      `xml:"datatype,attr"`
+		Units          []xliffUnit `xml:"body>trans-unit"`
+	}
+
+	xliffUnit struct {
+		ID     string      `xml:"id,attr"`
+		Source string      `xml:"source"`
+		Target xliffTarget `xml:"target"`
+		Note   string      `xml:"note,omitempty"`
+	}
+
+	xliffTarget struct {
+		State string `xml:"state,attr,omitempty"`
+		Text  string `xml:",chardata"`
+	}
+)
+
+// exportXLIFF writes one XLIFF 1.2 file per target language into the
+// given directory containing all reference strings and their existing
+// translations. Translations not reviewed by a human are exported as
+// needs-review-translation, reviewed ones as translated.
+func exportXLIFF(tf translationFile, dir string) error {
+	if !cfg.DryRun {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return errors.Wrap(err, "creating export directory")
+		}
+	}
+
+	for _, lang := range tf.Languages() {
+		doc := xliffDocument{
+			Version: xliffVersion,
+			Files:   []xliffFile{buildXLIFFFile(tf, lang)},
+		}
+
+		filename := filepath.Join(dir, lang+".xlf")
+		if err := writeFileAtomic(filename, func(w io.Writer) error {
+			if _, err := io.WriteString(w, xml.Header); err != nil {
+				return errors.Wrap(err, "writing XML header")
+			}
+
+			encoder := xml.NewEncoder(w)
+			encoder.Indent("", "  ")
+			if err := encoder.Encode(doc); err != nil {
+				return errors.Wrap(err, "encoding XLIFF")
+			}
+			_, err := io.WriteString(w, "\n")
+			return errors.Wrap(err, "writing XLIFF")
+		}); err != nil {
+			return errors.Wrapf(err, "writing language %s", lang)
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"lang":  lang,
+			"file":  filename,
+			"units": len(doc.Files[0].Units),
+		}).Info("exported XLIFF file")
+	}
+
+	return nil
+}
+
+func buildXLIFFFile(tf translationFile, lang string) xliffFile {
+	file := xliffFile{
+		Original:       filepath.Base(cfg.TranslationFile),
+		SourceLanguage: tf.Reference.LanguageKey,
+		TargetLanguage: lang,
+		Datatype:       "plaintext",
+	}
+
+	for _, key := range tf.ReferenceKeys() {
+		var (
+			sources = leafStrings(key, tf.Reference.Translations[key])
+			targets = leafStrings(key, tf.Translations[lang].Translations[key])
+			paths   = make([]string, 0, len(sources))
+		)
+
+		for path := range sources {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+
+		for _, path := range paths {
+			unit := xliffUnit{
+				ID:     path,
+				Source: sources[path],
+				Target: xliffTarget{State: xliffStateNew},
+				Note:   tf.Reference.Descriptions[strings.SplitN(path, ".", 2)[0]],
+			}
+
+			if target, ok := targets[path]; ok {
+				unit.Target = xliffTarget{State: xliffStateTranslated, Text: target}
+				if !tf.Translations[lang].isReviewed(path) {
+					unit.Target.State = xliffStateNeedsReview
+				}
+			}
+
+			file.Units = append(file.Units, unit)
+		}
+	}
+
+	return file
+}
+
+// importXLIFF reads all XLIFF files from the given directory and puts
+// their translated units into the translation file, marked as reviewed.
+// Units with an unchanged target are skipped unless their state is
+// final or signed-off, approving the existing translation.
+func importXLIFF(tf *translationFile, dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.xlf"))
+	if err != nil {
+		return errors.Wrap(err, "listing XLIFF files")
+	}
+
+	for _, filename := range files {
+		if err = importXLIFFFile(tf, filename); err != nil {
+			return errors.Wrapf(err, "importing %s", filename)
+		}
+	}
+
+	return nil
+}
+
+func importXLIFFFile(tf *translationFile, filename string) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return errors.Wrap(err, "opening file")
+	}
+	defer f.Close()
+
+	var doc xliffDocument
+	if err = xml.NewDecoder(f).Decode(&doc); err != nil {
+		return errors.Wrap(err, "decoding XLIFF")
+	}
+
+	for _, file := range doc.Files {
+		lang := normalizeLanguageKey(file.TargetLanguage)
+		if tf.Translations[lang] == nil {
+			logrus.WithFields(logrus.Fields{"file": filename, "lang": lang}).Warn("unknown target language, skipping")
+			continue
+		}
+
+		tm := tf.Translations[lang]
+
+		tasks, verbatim, err := importTasks(tf, lang)
+		if err != nil {
+			return errors.Wrap(err, "collecting strings")
+		}
+
+		current := tm.leafStrings()
+
+		var imported int
+		for _, unit := range file.Units {
+			if unit.Target.State == xliffStateNew || unit.Target.Text == "" {
+				continue
+			}
+
+			task, ok := tasks[unit.ID]
+			if !ok {
+				logrus.WithFields(logrus.Fields{"lang": lang, "id": unit.ID}).Warn("unit does not match any reference key, skipping")
+				continue
+			}
+
+			if verbatim[unit.ID] {
+				continue
+			}
+
+			if current[unit.ID] == unit.Target.Text {
+				approved := unit.Target.State == xliffStateFinal || unit.Target.State == xliffStateSignedOff
+				if !approved || tm.isReviewed(unit.ID) {
+					continue
+				}
+			} else {
+				task.apply(unit.Target.Text)
+			}
+
+			tm.setSourceHash(task.Path, task.Source)
+			tm.setProvenance(task.Path, provenanceReviewed)
+			imported++
+		}
+
+		logrus.WithFields(logrus.Fields{"lang": lang, "units": imported}).Info("imported XLIFF file")
+	}
+
+	return nil
+}
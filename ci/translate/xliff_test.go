@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestBuildXLIFFFileStates(t *testing.T) {
+	testConfig(t)
+
+	tf := loadTestFile(t, `reference:
+  languageKey: en
+  translations:
+    auto: Auto
+    missing: Missing
+    reviewed: Reviewed
+    unknown: Unknown
+translations:
+  de:
+    translations:
+      auto: Automatisch
+      reviewed: Geprüft
+      unknown: Unbekannt
+    meta:
+      provenance:
+        auto:
+          state: auto
+          updated: 2024-01-01T00:00:00Z
+        reviewed:
+          state: reviewed
+          updated: 2024-01-01T00:00:00Z
+`)
+
+	expected := map[string]xliffTarget{
+		"auto":     {State: xliffStateNeedsReview, Text: "Automatisch"},
+		"missing":  {State: xliffStateNew},
+		"reviewed": {State: xliffStateTranslated, Text: "Geprüft"},
+		"unknown":  {State: xliffStateNeedsReview, Text: "Unbekannt"},
+	}
+
+	file := buildXLIFFFile(tf, "de")
+	if len(file.Units) != len(expected) {
+		t.Fatalf("expected %d units, got %d", len(expected), len(file.Units))
+	}
+
+	for _, unit := range file.Units {
+		if unit.Target != expected[unit.ID] {
+			t.Errorf("unit %s: got %#v, want %#v", unit.ID, unit.Target, expected[unit.ID])
+		}
+	}
+}
+
+func TestXLIFFRoundTripKeepsProvenance(t *testing.T) {
+	testConfig(t)
+
+	tf := loadTestFile(t, testProvenanceYAML)
+	dir := t.TempDir()
+
+	if err := exportXLIFF(tf, dir); err != nil {
+		t.Fatalf("exporting: %s", err)
+	}
+
+	before := tf.Translations["de"].Meta.Provenance
+	if err := importXLIFF(&tf, dir); err != nil {
+		t.Fatalf("importing: %s", err)
+	}
+
+	if after := tf.Translations["de"].Meta.Provenance; !reflect.DeepEqual(before, after) {
+		t.Errorf("provenance changed by round-trip:\n got: %#v\nwant: %#v", after, before)
+	}
+}
+
+func TestImportXLIFFUnits(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		unit     xliffUnit
+		value    string
+		reviewed bool
+	}{
+		{
+			name:  "new",
+			unit:  xliffUnit{ID: "auto", Target: xliffTarget{State: xliffStateNew, Text: "Neu"}},
+			value: "Alt",
+		},
+		{
+			name:  "unchanged",
+			unit:  xliffUnit{ID: "auto", Target: xliffTarget{State: xliffStateTranslated, Text: "Alt"}},
+			value: "Alt",
+		},
+		{
+			name:  "unchanged needing review",
+			unit:  xliffUnit{ID: "auto", Target: xliffTarget{State: xliffStateNeedsReview, Text: "Alt"}},
+			value: "Alt",
+		},
+		{
+			name:     "unchanged final",
+			unit:     xliffUnit{ID: "auto", Target: xliffTarget{State: xliffStateFinal, Text: "Alt"}},
+			value:    "Alt",
+			reviewed: true,
+		},
+		{
+			name:     "unchanged signed-off",
+			unit:     xliffUnit{ID: "auto", Target: xliffTarget{State: xliffStateSignedOff, Text: "Alt"}},
+			value:    "Alt",
+			reviewed: true,
+		},
+		{
+			name:     "changed",
+			unit:     xliffUnit{ID: "auto", Target: xliffTarget{State: xliffStateNeedsReview, Text: "Neu"}},
+			value:    "Neu",
+			reviewed: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			testConfig(t)
+
+			tf := loadTestFile(t, testProvenanceYAML)
+			filename := filepath.Join(t.TempDir(), "de.xlf")
+			writeTestXLIFF(t, filename, xliffFile{TargetLanguage: "de", Units: []xliffUnit{tc.unit}})
+
+			if err := importXLIFFFile(&tf, filename); err != nil {
+				t.Fatalf("importing: %s", err)
+			}
+
+			de := tf.Translations["de"]
+			if got := de.Translations["auto"]; got != tc.value {
+				t.Errorf("value = %q, want %q", got, tc.value)
+			}
+			if de.isReviewed("auto") != tc.reviewed {
+				t.Errorf("reviewed = %v, want %v", de.isReviewed("auto"), tc.reviewed)
+			}
+		})
+	}
+}
+
+func TestImportXLIFFLanguageIsNormalized(t *testing.T) {
+	for _, lang := range []string{"pt-BR", "pt_BR", "pt-br", "ptBR"} {
+		t.Run(lang, func(t *testing.T) {
+			testConfig(t)
+
+			tf := loadTestFile(t, testRegionYAML)
+			filename := filepath.Join(t.TempDir(), "messages.xlf")
+			writeTestXLIFF(t, filename, xliffFile{TargetLanguage: lang, Units: []xliffUnit{
+				{ID: "greeting", Target: xliffTarget{State: xliffStateTranslated, Text: "Olá"}},
+			}})
+
+			if err := importXLIFFFile(&tf, filename); err != nil {
+				t.Fatalf("importing: %s", err)
+			}
+
+			if got := tf.Translations["pt-BR"].Translations["greeting"]; got != "Olá" {
+				t.Errorf("greeting = %v, want Olá", got)
+			}
+		})
+	}
+}
+
+func writeTestXLIFF(t *testing.T, filename string, file xliffFile) {
+	t.Helper()
+
+	data, err := xml.Marshal(xliffDocument{Version: xliffVersion, Files: []xliffFile{file}})
+	if err != nil {
+		t.Fatalf("encoding XLIFF: %s", err)
+	}
+
+	if err = os.WriteFile(filename, data, 0o600); err != nil {
+		t.Fatalf("writing XLIFF: %s", err)
+	}
+}